@@ -0,0 +1,250 @@
+package sse
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+)
+
+// DefaultBrokerBufferSize is the number of events queued for each client of a
+// Broker when BufferSize is not set.
+const DefaultBrokerBufferSize = 64
+
+// Broker is an http.Handler which streams every published Event to all
+// connected clients.
+//
+// Each request served by the Broker is registered as a client until its
+// context is cancelled, i.e. until the client disconnects or the server shuts
+// down. Events are queued per client; a client which falls more than
+// BufferSize events behind is disconnected rather than blocking Publish, and
+// is expected to reconnect (resuming with Last-Event-ID).
+//
+// If Replay is set, every published event is recorded in it, and a client
+// reconnecting with a Last-Event-ID header is first sent the recorded events
+// which followed that ID. If the ID is no longer retained, the client is sent
+// every retained event, as it has already missed events it can no longer be
+// sent; how much that is depends on the store's retention, so an unbounded
+// FileStore sends its entire history. If the store fails, the request is
+// rejected with 500 Internal Server Error.
+//
+// The zero value is ready to use.
+type Broker struct {
+	// BufferSize is the number of events queued for each client before it is
+	// considered too slow and disconnected. Defaults to
+	// DefaultBrokerBufferSize.
+	BufferSize int
+
+	// Replay records published events for clients resuming with a
+	// Last-Event-ID, e.g. a ReplayBuffer or a FileStore. Optional.
+	Replay EventStore
+
+	lock    sync.Mutex
+	clients map[*brokerClient]struct{}
+}
+
+type brokerClient struct {
+	// topics the client is subscribed to; nil means all events
+	topics []string
+
+	events  chan Event
+	dropped chan struct{}
+}
+
+func (client *brokerClient) subscribed(topic string) bool {
+	if client.topics == nil {
+		return true
+	}
+
+	for _, pattern := range client.topics {
+		if TopicMatches(pattern, topic) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ServeHTTP registers the request as a client and streams events to it until
+// the request's context is cancelled.
+func (broker *Broker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	broker.serve(w, r, broker.BufferSize, nil)
+}
+
+// Publish queues the event for every connected client. If Replay is set, the
+// event is recorded in it first; if that fails the event is still delivered
+// and the error is returned. An invalid event is not published, and a
+// *ValidationError is returned.
+func (broker *Broker) Publish(event Event) error {
+	return broker.publish("", event)
+}
+
+// Clients returns the number of currently connected clients.
+func (broker *Broker) Clients() int {
+	broker.lock.Lock()
+	defer broker.lock.Unlock()
+
+	return len(broker.clients)
+}
+
+func (broker *Broker) serve(w http.ResponseWriter, r *http.Request, bufferSize int, topics []string) {
+	client, backlog, err := broker.register(bufferSize, topics, r.Header.Get("Last-Event-ID"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	defer broker.unregister(client)
+
+	broker.stream(w, r, client, backlog)
+}
+
+func (broker *Broker) publish(topic string, event Event) error {
+	if err := event.Validate(); err != nil {
+		return err
+	}
+
+	broker.lock.Lock()
+	defer broker.lock.Unlock()
+
+	var err error
+	if broker.Replay != nil {
+		err = broker.Replay.Append(event)
+	}
+
+	for client := range broker.clients {
+		if client.subscribed(topic) {
+			broker.deliver(client, event)
+		}
+	}
+
+	return err
+}
+
+func (broker *Broker) register(bufferSize int, topics []string, lastEventID string) (*brokerClient, []Event, error) {
+	if bufferSize <= 0 {
+		bufferSize = DefaultBrokerBufferSize
+	}
+
+	client := &brokerClient{
+		topics:  topics,
+		events:  make(chan Event, bufferSize),
+		dropped: make(chan struct{}),
+	}
+
+	broker.lock.Lock()
+
+	// find the backlog while holding the lock so that no event is missed or
+	// sent twice between replaying and registering for live events
+	read, err := broker.backlog(lastEventID)
+	if err != nil {
+		broker.lock.Unlock()
+		return nil, nil, err
+	}
+
+	if broker.clients == nil {
+		broker.clients = map[*brokerClient]struct{}{}
+	}
+
+	broker.clients[client] = struct{}{}
+
+	broker.lock.Unlock()
+
+	// read it without the lock, as the store may have to go to disk; live
+	// events are queued for the client in the meantime
+	backlog, err := read()
+	if err != nil {
+		broker.unregister(client)
+		return nil, nil, err
+	}
+
+	return client, backlog, nil
+}
+
+// backlog must be called with the lock held. It returns a function which
+// reads the events to replay to a client resuming from the given ID.
+func (broker *Broker) backlog(lastEventID string) (func() ([]Event, error), error) {
+	if broker.Replay == nil || lastEventID == "" {
+		return func() ([]Event, error) { return nil, nil }, nil
+	}
+
+	since := func(lastEventID string) (func() ([]Event, error), error) {
+		if store, ok := broker.Replay.(deferredStore); ok {
+			return store.since(lastEventID)
+		}
+
+		events, err := broker.Replay.Since(lastEventID)
+		return func() ([]Event, error) { return events, nil }, err
+	}
+
+	read, err := since(lastEventID)
+	if errors.Is(err, ErrEventNotFound) {
+		// the client has missed more than we retained; send what we have
+		read, err = since("")
+	}
+
+	return read, err
+}
+
+func (broker *Broker) unregister(client *brokerClient) {
+	broker.lock.Lock()
+	defer broker.lock.Unlock()
+
+	delete(broker.clients, client)
+}
+
+// deliver must be called with the lock held.
+func (broker *Broker) deliver(client *brokerClient, event Event) {
+	select {
+	case client.events <- event:
+	default:
+		// client is too slow; disconnect it rather than block everyone else
+		delete(broker.clients, client)
+		close(client.dropped)
+	}
+}
+
+func (broker *Broker) stream(w http.ResponseWriter, r *http.Request, client *brokerClient, backlog []Event) {
+	w.Header().Set("Content-Type", "text/event-stream; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+
+	w.WriteHeader(http.StatusOK)
+
+	encoder := NewEncoder(w)
+	encoder.ManualFlush = true
+
+	for _, event := range backlog {
+		if err := encoder.Encode(event); err != nil {
+			return
+		}
+	}
+
+	if err := encoder.Flush(); err != nil {
+		return
+	}
+
+	for {
+		select {
+		case event := <-client.events:
+			if err := encoder.Encode(event); err != nil {
+				return
+			}
+
+			// write everything already queued before flushing
+			for len(client.events) > 0 {
+				if err := encoder.Encode(<-client.events); err != nil {
+					return
+				}
+			}
+
+			if err := encoder.Flush(); err != nil {
+				return
+			}
+
+		case <-client.dropped:
+			return
+
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
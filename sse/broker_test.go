@@ -0,0 +1,251 @@
+package sse_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/vito/go-sse/sse"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type blockingResponseWriter struct {
+	header  http.Header
+	writing chan struct{}
+	release chan struct{}
+}
+
+func (w *blockingResponseWriter) Header() http.Header { return w.header }
+func (w *blockingResponseWriter) WriteHeader(int)     {}
+func (w *blockingResponseWriter) Flush()              {}
+
+func (w *blockingResponseWriter) Write(p []byte) (int, error) {
+	select {
+	case w.writing <- struct{}{}:
+	default:
+	}
+
+	<-w.release
+
+	return len(p), nil
+}
+
+var _ = Describe("Broker", func() {
+	var (
+		broker *Broker
+		server *httptest.Server
+	)
+
+	BeforeEach(func() {
+		broker = &Broker{}
+		server = httptest.NewServer(broker)
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	connect := func() *EventSource {
+		source, err := Connect(http.DefaultClient, 100*time.Millisecond, func() *http.Request {
+			request, err := http.NewRequest("GET", server.URL, nil)
+			Ω(err).ShouldNot(HaveOccurred())
+			return request
+		})
+		Ω(err).ShouldNot(HaveOccurred())
+
+		return source
+	}
+
+	It("responds with event stream headers", func() {
+		request, err := http.NewRequest("GET", server.URL, nil)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		response, err := http.DefaultClient.Do(request)
+		Ω(err).ShouldNot(HaveOccurred())
+		defer response.Body.Close()
+
+		Ω(response.StatusCode).Should(Equal(http.StatusOK))
+		Ω(response.Header.Get("Content-Type")).Should(Equal("text/event-stream; charset=utf-8"))
+		Ω(response.Header.Get("Cache-Control")).Should(ContainSubstring("no-cache"))
+	})
+
+	It("fans out published events to every connected client", func() {
+		source1 := connect()
+		defer source1.Close()
+
+		source2 := connect()
+		defer source2.Close()
+
+		Ω(broker.Clients()).Should(Equal(2))
+
+		broker.Publish(Event{ID: "1", Data: []byte("hello")})
+		broker.Publish(Event{ID: "2", Data: []byte("hello again")})
+
+		for _, source := range []*EventSource{source1, source2} {
+			Ω(source.Next()).Should(Equal(Event{ID: "1", Data: []byte("hello")}))
+			Ω(source.Next()).Should(Equal(Event{ID: "2", Data: []byte("hello again")}))
+		}
+	})
+
+	It("rejects invalid events without disconnecting clients", func() {
+		source := connect()
+		defer source.Close()
+
+		var validationErr *ValidationError
+		Ω(errors.As(broker.Publish(Event{ID: "1\ndata: forged", Data: []byte("hello")}), &validationErr)).Should(BeTrue())
+		Ω(validationErr.Field).Should(Equal("id"))
+
+		Ω(broker.Publish(Event{ID: "2", Data: []byte("hello again")})).Should(Succeed())
+		Ω(source.Next()).Should(Equal(Event{ID: "2", Data: []byte("hello again")}))
+	})
+
+	It("unregisters clients when they disconnect", func() {
+		source := connect()
+		Ω(broker.Clients()).Should(Equal(1))
+
+		Ω(source.Close()).Should(Succeed())
+
+		Eventually(broker.Clients).Should(BeZero())
+	})
+
+	Context("with a replay buffer", func() {
+		resume := func(lastEventID string) *EventSource {
+			source, err := Connect(http.DefaultClient, 100*time.Millisecond, func() *http.Request {
+				request, err := http.NewRequest("GET", server.URL, nil)
+				Ω(err).ShouldNot(HaveOccurred())
+				request.Header.Set("Last-Event-ID", lastEventID)
+				return request
+			})
+			Ω(err).ShouldNot(HaveOccurred())
+
+			return source
+		}
+
+		BeforeEach(func() {
+			broker.Replay = NewReplayBuffer(2, 0)
+
+			Ω(broker.Publish(Event{ID: "1", Data: []byte("one")})).Should(Succeed())
+			Ω(broker.Publish(Event{ID: "2", Data: []byte("two")})).Should(Succeed())
+			Ω(broker.Publish(Event{ID: "3", Data: []byte("three")})).Should(Succeed())
+		})
+
+		It("replays the events after the client's Last-Event-ID before live events", func() {
+			source := resume("2")
+			defer source.Close()
+
+			broker.Publish(Event{ID: "4", Data: []byte("four")})
+
+			Ω(source.Next()).Should(Equal(Event{ID: "3", Data: []byte("three")}))
+			Ω(source.Next()).Should(Equal(Event{ID: "4", Data: []byte("four")}))
+		})
+
+		It("replays every retained event when the ID is no longer retained", func() {
+			source := resume("1")
+			defer source.Close()
+
+			Ω(source.Next()).Should(Equal(Event{ID: "2", Data: []byte("two")}))
+			Ω(source.Next()).Should(Equal(Event{ID: "3", Data: []byte("three")}))
+		})
+
+		It("does not replay anything to new clients", func() {
+			source := connect()
+			defer source.Close()
+
+			broker.Publish(Event{ID: "4", Data: []byte("four")})
+
+			Ω(source.Next()).Should(Equal(Event{ID: "4", Data: []byte("four")}))
+		})
+	})
+
+	Context("with a file store", func() {
+		var store *FileStore
+
+		BeforeEach(func() {
+			var err error
+			store, err = OpenFileStore(GinkgoT().TempDir(), FileStoreOptions{})
+			Ω(err).ShouldNot(HaveOccurred())
+
+			broker.Replay = store
+
+			Ω(broker.Publish(Event{ID: "1", Data: []byte("one")})).Should(Succeed())
+			Ω(broker.Publish(Event{ID: "2", Data: []byte("two")})).Should(Succeed())
+		})
+
+		AfterEach(func() {
+			store.Close()
+		})
+
+		resume := func(lastEventID string) *EventSource {
+			source, err := Connect(http.DefaultClient, 100*time.Millisecond, func() *http.Request {
+				request, err := http.NewRequest("GET", server.URL, nil)
+				Ω(err).ShouldNot(HaveOccurred())
+				request.Header.Set("Last-Event-ID", lastEventID)
+				return request
+			})
+			Ω(err).ShouldNot(HaveOccurred())
+
+			return source
+		}
+
+		It("replays each event once, before live events", func() {
+			source := resume("1")
+			defer source.Close()
+
+			broker.Publish(Event{ID: "3", Data: []byte("three")})
+
+			Ω(source.Next()).Should(Equal(Event{ID: "2", Data: []byte("two")}))
+			Ω(source.Next()).Should(Equal(Event{ID: "3", Data: []byte("three")}))
+
+			broker.Publish(Event{ID: "4", Data: []byte("four")})
+
+			Ω(source.Next()).Should(Equal(Event{ID: "4", Data: []byte("four")}))
+		})
+
+		It("replays every retained event when the ID is unknown", func() {
+			source := resume("bogus")
+			defer source.Close()
+
+			Ω(source.Next()).Should(Equal(Event{ID: "1", Data: []byte("one")}))
+			Ω(source.Next()).Should(Equal(Event{ID: "2", Data: []byte("two")}))
+		})
+	})
+
+	Context("when a client falls too far behind", func() {
+		BeforeEach(func() {
+			broker.BufferSize = 1
+		})
+
+		It("disconnects it", func() {
+			writer := &blockingResponseWriter{
+				header:  http.Header{},
+				writing: make(chan struct{}, 1),
+				release: make(chan struct{}),
+			}
+
+			request := httptest.NewRequest("GET", "/", nil)
+
+			served := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				broker.ServeHTTP(writer, request)
+				close(served)
+			}()
+
+			Eventually(broker.Clients).Should(Equal(1))
+
+			broker.Publish(Event{ID: "1", Data: []byte("taken")})
+			Eventually(writer.writing).Should(Receive())
+
+			broker.Publish(Event{ID: "2", Data: []byte("queued")})
+			broker.Publish(Event{ID: "3", Data: []byte("overflow")})
+
+			Ω(broker.Clients()).Should(BeZero())
+
+			close(writer.release)
+			Eventually(served).Should(BeClosed())
+		})
+	})
+})
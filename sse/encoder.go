@@ -0,0 +1,171 @@
+package sse
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Encoder writes events to a stream, such as an http.ResponseWriter.
+//
+// Each event, comment, or retry directive is written with a single call to the
+// underlying writer and then flushed, so that it reaches the client
+// immediately. Flushing uses http.ResponseController for an
+// http.ResponseWriter, and otherwise a Flush method if the writer has one.
+//
+// An Encoder is not safe for concurrent use, except for its counters.
+type Encoder struct {
+	// ManualFlush disables flushing after every write, so that several events
+	// can be batched into one flush by calling Flush.
+	ManualFlush bool
+
+	w     io.Writer
+	flush func() error
+
+	// reused between writes
+	buf []byte
+
+	events atomic.Uint64
+	bytes  atomic.Uint64
+}
+
+// NewEncoder returns an Encoder which writes to w.
+func NewEncoder(w io.Writer) *Encoder {
+	encoder := &Encoder{w: w}
+
+	switch flusher := w.(type) {
+	case http.ResponseWriter:
+		encoder.flush = http.NewResponseController(flusher).Flush
+	case http.Flusher:
+		encoder.flush = func() error {
+			flusher.Flush()
+			return nil
+		}
+	case interface{ Flush() error }:
+		encoder.flush = flusher.Flush
+	}
+
+	return encoder
+}
+
+// Encode writes the event. If the event is invalid, nothing is written and a
+// *ValidationError is returned.
+func (encoder *Encoder) Encode(event Event) error {
+	if err := event.Validate(); err != nil {
+		return err
+	}
+
+	err := encoder.write(appendEvent(encoder.buf[:0], event))
+	if err != nil {
+		return err
+	}
+
+	encoder.events.Add(1)
+
+	return encoder.autoFlush()
+}
+
+// Comment writes a comment, which clients ignore, e.g. as a heartbeat to keep
+// the connection alive. Each line of the text is written as a separate
+// comment line, splitting on CR, LF, and CRLF alike.
+func (encoder *Encoder) Comment(text string) error {
+	buf := encoder.buf[:0]
+	for {
+		end := strings.IndexAny(text, "\r\n")
+
+		line := text
+		if end != -1 {
+			line = text[:end]
+		}
+
+		buf = append(buf, ':')
+		if line != "" {
+			buf = append(buf, ' ')
+			buf = append(buf, line...)
+		}
+		buf = append(buf, '\n')
+
+		if end == -1 {
+			break
+		}
+
+		if text[end] == '\r' && end+1 < len(text) && text[end+1] == '\n' {
+			end++
+		}
+
+		text = text[end+1:]
+	}
+
+	err := encoder.write(buf)
+	if err != nil {
+		return err
+	}
+
+	return encoder.autoFlush()
+}
+
+// Retry tells the client how long to wait before reconnecting, without
+// dispatching an event. If the delay is negative, nothing is written and a
+// *ValidationError is returned.
+func (encoder *Encoder) Retry(delay time.Duration) error {
+	if err := (Event{Retry: delay}).Validate(); err != nil {
+		return err
+	}
+
+	buf := append(encoder.buf[:0], retryPrefix...)
+	buf = strconv.AppendInt(buf, delay.Milliseconds(), 10)
+	buf = append(buf, "\n\n"...)
+
+	err := encoder.write(buf)
+	if err != nil {
+		return err
+	}
+
+	return encoder.autoFlush()
+}
+
+// Flush flushes the underlying writer, if it can be flushed.
+func (encoder *Encoder) Flush() error {
+	if encoder.flush == nil {
+		return nil
+	}
+
+	err := encoder.flush()
+	if errors.Is(err, http.ErrNotSupported) {
+		return nil
+	}
+
+	return err
+}
+
+// EventsWritten returns the number of events written.
+func (encoder *Encoder) EventsWritten() uint64 {
+	return encoder.events.Load()
+}
+
+// BytesWritten returns the number of bytes written, including comments and
+// retry directives.
+func (encoder *Encoder) BytesWritten() uint64 {
+	return encoder.bytes.Load()
+}
+
+func (encoder *Encoder) write(buf []byte) error {
+	encoder.buf = buf
+
+	n, err := encoder.w.Write(buf)
+	encoder.bytes.Add(uint64(n))
+
+	return err
+}
+
+func (encoder *Encoder) autoFlush() error {
+	if encoder.ManualFlush {
+		return nil
+	}
+
+	return encoder.Flush()
+}
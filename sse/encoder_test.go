@@ -0,0 +1,152 @@
+package sse_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/vito/go-sse/sse"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type countingWriter struct {
+	bytes.Buffer
+
+	writes  int
+	flushes int
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.writes++
+	return w.Buffer.Write(p)
+}
+
+func (w *countingWriter) Flush() error {
+	w.flushes++
+	return nil
+}
+
+var _ = Describe("Encoder", func() {
+	var (
+		destination *countingWriter
+		encoder     *Encoder
+	)
+
+	BeforeEach(func() {
+		destination = &countingWriter{}
+		encoder = NewEncoder(destination)
+	})
+
+	Describe("Encode", func() {
+		event := Event{
+			ID:   "some-id",
+			Name: "some-name",
+			Data: []byte("some-data\nsome-more-data"),
+		}
+
+		It("writes each event in a single write and flushes it", func() {
+			Ω(encoder.Encode(event)).Should(Succeed())
+			Ω(encoder.Encode(event)).Should(Succeed())
+
+			Ω(destination.String()).Should(Equal(event.Encode() + event.Encode()))
+			Ω(destination.writes).Should(Equal(2))
+			Ω(destination.flushes).Should(Equal(2))
+		})
+
+		It("counts the events and bytes written", func() {
+			Ω(encoder.Encode(event)).Should(Succeed())
+			Ω(encoder.Comment("heartbeat")).Should(Succeed())
+
+			Ω(encoder.EventsWritten()).Should(Equal(uint64(1)))
+			Ω(encoder.BytesWritten()).Should(Equal(uint64(destination.Len())))
+		})
+
+		It("rejects invalid events", func() {
+			var validationErr *ValidationError
+			Ω(errors.As(encoder.Encode(Event{Name: "a\nb"}), &validationErr)).Should(BeTrue())
+
+			Ω(destination.Len()).Should(BeZero())
+			Ω(encoder.EventsWritten()).Should(BeZero())
+		})
+
+		Context("when flushing manually", func() {
+			BeforeEach(func() {
+				encoder.ManualFlush = true
+			})
+
+			It("only flushes when told to", func() {
+				Ω(encoder.Encode(event)).Should(Succeed())
+				Ω(encoder.Encode(event)).Should(Succeed())
+				Ω(destination.flushes).Should(BeZero())
+
+				Ω(encoder.Flush()).Should(Succeed())
+				Ω(destination.flushes).Should(Equal(1))
+			})
+		})
+	})
+
+	Describe("Comment", func() {
+		It("writes each line as a comment", func() {
+			Ω(encoder.Comment("heartbeat")).Should(Succeed())
+			Ω(encoder.Comment("two\nlines")).Should(Succeed())
+			Ω(encoder.Comment("")).Should(Succeed())
+
+			Ω(destination.String()).Should(Equal(": heartbeat\n: two\n: lines\n:\n"))
+			Ω(destination.flushes).Should(Equal(3))
+		})
+
+		It("splits on CR and CRLF too", func() {
+			Ω(encoder.Comment("a\rdata: forged\r\nb")).Should(Succeed())
+			Ω(destination.String()).Should(Equal(": a\n: data: forged\n: b\n"))
+		})
+
+		It("is ignored by the reader", func() {
+			Ω(encoder.Comment("heartbeat")).Should(Succeed())
+			Ω(encoder.Encode(Event{Data: []byte("hello")})).Should(Succeed())
+
+			Ω(NewReadCloser(io.NopCloser(&destination.Buffer)).Next()).Should(Equal(Event{Data: []byte("hello")}))
+			Ω(encoder.EventsWritten()).Should(Equal(uint64(1)))
+		})
+	})
+
+	Describe("Retry", func() {
+		It("writes a retry directive without dispatching an event", func() {
+			Ω(encoder.Retry(1500 * time.Millisecond)).Should(Succeed())
+
+			Ω(destination.String()).Should(Equal("retry: 1500\n\n"))
+			Ω(destination.flushes).Should(Equal(1))
+			Ω(encoder.EventsWritten()).Should(BeZero())
+		})
+
+		It("rejects negative delays without writing anything", func() {
+			var validationErr *ValidationError
+			Ω(errors.As(encoder.Retry(-5*time.Second), &validationErr)).Should(BeTrue())
+			Ω(validationErr.Field).Should(Equal("retry"))
+
+			Ω(destination.String()).Should(BeEmpty())
+		})
+	})
+
+	Context("with an http.ResponseWriter", func() {
+		It("flushes the response", func() {
+			recorder := httptest.NewRecorder()
+
+			Ω(NewEncoder(recorder).Encode(Event{Data: []byte("hello")})).Should(Succeed())
+			Ω(recorder.Flushed).Should(BeTrue())
+			Ω(recorder.Body.String()).Should(Equal("data: hello\n\n"))
+		})
+	})
+
+	Context("with a writer which cannot be flushed", func() {
+		It("writes without flushing", func() {
+			var buffer bytes.Buffer
+
+			Ω(NewEncoder(&buffer).Encode(Event{Data: []byte("hello")})).Should(Succeed())
+			Ω(buffer.String()).Should(Equal("data: hello\n\n"))
+		})
+	})
+})
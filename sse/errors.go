@@ -3,3 +3,17 @@ package sse
 import "errors"
 
 var ErrSourceClosed = errors.New("source closed")
+
+var ErrEventNotFound = errors.New("event not found")
+
+// ErrNoContent is returned when the server responds with 204 No Content,
+// which tells the client to stop reconnecting.
+var ErrNoContent = errors.New("event source responded with no content")
+
+// ErrEventTooLarge is matched by the EventTooLargeError returned when an event
+// exceeds the limits set by ReaderOptions.
+var ErrEventTooLarge = errors.New("event too large")
+
+// ErrIdleTimeout is reported when a connection is dropped because nothing was
+// received within Config.IdleTimeout.
+var ErrIdleTimeout = errors.New("event source idle timeout")
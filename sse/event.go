@@ -2,14 +2,21 @@ package sse
 
 import (
 	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
+	"unicode/utf8"
 )
 
 // Define byte slice constants used in encoding/writing
 var (
 	idPrefix    = []byte("id: ")
+	emptyID     = []byte("id")
 	eventPrefix = []byte("event: ")
 	retryPrefix = []byte("retry: ")
 	dataPrefix  = []byte("data: ")
@@ -23,107 +30,294 @@ type Event struct {
 	Name  string
 	Data  []byte
 	Retry time.Duration
+
+	// ResetID is set when the event explicitly resets the client's last
+	// event ID with an empty id field. An empty ID is otherwise omitted when
+	// encoding, which leaves the client's last event ID as it was.
+	ResetID bool
+
+	// Fields holds any fields other than the above, in the order they
+	// appeared. They are only collected when reading with
+	// ReaderOptions.KeepUnknownFields, and are written before the data.
+	Fields []Field
 }
 
-func (event Event) Encode() string {
-	// Make an educated capacity estimate
-	capacity := 8 + len(event.ID) + 8 + len(event.Name) + 20
-	dataLines := bytes.Count(event.Data, newline) + 1
-	capacity += len(event.Data) + (dataLines * 7)
+// Field is a field of an event which is not defined by the spec, e.g. a
+// vendor extension.
+type Field struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
 
-	var buf bytes.Buffer
-	buf.Grow(capacity)
+// ValidationError is returned when an event cannot be encoded without its
+// fields being misinterpreted by the client, e.g. an ID containing a newline,
+// which would let its contents forge further fields or events.
+type ValidationError struct {
+	// Field is the name of the invalid field, e.g. "id" or "event".
+	Field string
 
-	// Use the pre-defined constants
-	buf.Write(idPrefix)
-	buf.WriteString(event.ID)
-	buf.Write(newline)
+	// Reason describes what is wrong with it.
+	Reason string
+}
 
-	buf.Write(eventPrefix)
-	buf.WriteString(event.Name)
-	buf.Write(newline)
+func (err *ValidationError) Error() string {
+	return fmt.Sprintf("invalid event %s: %s", err.Field, err.Reason)
+}
 
-	if event.Retry != 0 {
-		buf.Write(retryPrefix)
-		buf.WriteString(strconv.FormatInt(int64(event.Retry/1000/1000), 10))
-		buf.Write(newline)
+// Validate returns a *ValidationError if the event cannot be encoded as is.
+//
+// The ID and the name may not contain CR, LF, or NULL, and the values of
+// extra fields may not contain CR or LF. Extra fields must have a name which
+// does not contain a colon and is not one of the fields defined by the spec.
+// The retry delay may not be negative. Data is always valid, as each line of
+// it is written as a separate data field.
+func (event Event) Validate() error {
+	if strings.ContainsAny(event.ID, "\r\n\x00") {
+		return &ValidationError{Field: "id", Reason: "contains CR, LF, or NULL"}
 	}
 
-	for _, line := range bytes.Split(event.Data, []byte("\n")) {
-		if len(line) == 0 {
-			buf.Write(emptyData)
-		} else {
-			buf.Write(dataPrefix)
-			buf.Write(line)
+	if strings.ContainsAny(event.Name, "\r\n\x00") {
+		return &ValidationError{Field: "event", Reason: "contains CR, LF, or NULL"}
+	}
+
+	if event.Retry < 0 {
+		return &ValidationError{Field: "retry", Reason: "is negative"}
+	}
+
+	for _, field := range event.Fields {
+		if !validFieldName(field.Name) {
+			return &ValidationError{Field: field.Name, Reason: "is not a valid extra field name"}
+		}
+
+		if strings.ContainsAny(field.Value, "\r\n") {
+			return &ValidationError{Field: field.Name, Reason: "contains CR or LF"}
 		}
-		buf.Write(newline)
 	}
 
-	buf.Write(newline)
+	return nil
+}
 
-	return buf.String()
+// Encode returns the event in the wire format, terminated by the blank line
+// which dispatches it. The id and event fields are omitted when empty, unless
+// ResetID is set.
+//
+// Encode does not fail on an invalid event, but sanitizes it instead: CR, LF,
+// and NULL are removed from the ID and the name, CR and LF from extra field
+// values, and invalid extra fields and retry delays are omitted. Use Validate
+// or Write to detect invalid events instead.
+func (event Event) Encode() string {
+	return string(appendEvent(nil, event))
 }
 
+// Write writes the encoded event to the destination in a single call. If the
+// event is invalid, nothing is written and a *ValidationError is returned.
 func (event Event) Write(destination io.Writer) error {
-	// Write id
-	if _, err := destination.Write(idPrefix); err != nil {
+	if err := event.Validate(); err != nil {
 		return err
 	}
-	if _, err := destination.Write([]byte(event.ID)); err != nil {
-		return err
+
+	_, err := destination.Write(appendEvent(nil, event))
+	return err
+}
+
+// MarshalText returns the event in the wire format, as written by Write.
+//
+// The wire format cannot distinguish nil Data from empty Data, as an event is
+// always written with at least one data field, so an event with nil Data is
+// unmarshaled with empty Data.
+func (event Event) MarshalText() ([]byte, error) {
+	if err := event.Validate(); err != nil {
+		return nil, err
 	}
-	if _, err := destination.Write(newline); err != nil {
-		return err
+
+	return appendEvent(nil, event), nil
+}
+
+// UnmarshalText parses the first event in the wire format, as read by
+// ReadCloser, keeping any unknown fields and any data which is not valid
+// UTF-8 as is.
+func (event *Event) UnmarshalText(text []byte) error {
+	reader := NewReadCloserWithOptions(io.NopCloser(bytes.NewReader(text)), ReaderOptions{
+		KeepUnknownFields: true,
+		InvalidUTF8:       PassInvalidUTF8,
+	})
+
+	parsed, err := reader.Next()
+	if err == io.EOF {
+		return fmt.Errorf("unmarshal event: %w", io.ErrUnexpectedEOF)
 	}
 
-	// Write event
-	if _, err := destination.Write(eventPrefix); err != nil {
+	if err != nil {
 		return err
 	}
-	if _, err := destination.Write([]byte(event.Name)); err != nil {
-		return err
+
+	*event = parsed
+
+	return nil
+}
+
+// eventJSON is the JSON representation of an Event. Data which is valid UTF-8
+// is represented as a string, and as base64 otherwise.
+type eventJSON struct {
+	ID         string  `json:"id,omitempty"`
+	ResetID    bool    `json:"reset_id,omitempty"`
+	Name       string  `json:"event,omitempty"`
+	Data       *string `json:"data,omitempty"`
+	DataBase64 []byte  `json:"data_base64,omitempty"`
+	Retry      int64   `json:"retry,omitempty"`
+	Fields     []Field `json:"fields,omitempty"`
+}
+
+// MarshalJSON encodes the event as an object with the fields "id",
+// "reset_id", "event", "data" (or "data_base64" if it is not valid UTF-8),
+// "retry" (in milliseconds), and "fields", each omitted when empty.
+func (event Event) MarshalJSON() ([]byte, error) {
+	encoded := eventJSON{
+		ID:      event.ID,
+		ResetID: event.ResetID,
+		Name:    event.Name,
+		Retry:   event.Retry.Milliseconds(),
+		Fields:  event.Fields,
 	}
-	if _, err := destination.Write(newline); err != nil {
+
+	if event.Data != nil {
+		if utf8.Valid(event.Data) {
+			data := string(event.Data)
+			encoded.Data = &data
+		} else {
+			encoded.DataBase64 = event.Data
+		}
+	}
+
+	return json.Marshal(encoded)
+}
+
+// UnmarshalJSON decodes an event encoded by MarshalJSON.
+func (event *Event) UnmarshalJSON(data []byte) error {
+	var decoded eventJSON
+	err := json.Unmarshal(data, &decoded)
+	if err != nil {
 		return err
 	}
 
-	// Write retry if present
-	if event.Retry != 0 {
-		if _, err := destination.Write(retryPrefix); err != nil {
-			return err
-		}
+	if decoded.Data != nil && decoded.DataBase64 != nil {
+		return errors.New("unmarshal event: both data and data_base64 are set")
+	}
 
-		retryValue := strconv.FormatInt(int64(event.Retry/1000/1000), 10)
-		if _, err := destination.Write([]byte(retryValue)); err != nil {
-			return err
-		}
+	*event = Event{
+		ID:      decoded.ID,
+		ResetID: decoded.ResetID,
+		Name:    decoded.Name,
+		Retry:   time.Duration(decoded.Retry) * time.Millisecond,
+		Fields:  decoded.Fields,
+	}
+
+	if decoded.Data != nil {
+		event.Data = []byte(*decoded.Data)
+	} else if decoded.DataBase64 != nil {
+		event.Data = decoded.DataBase64
+	}
+
+	return nil
+}
+
+func appendEvent(buf []byte, event Event) []byte {
+	// Make an educated capacity estimate
+	capacity := 8 + len(event.ID) + 8 + len(event.Name) + 20
+	dataLines := bytes.Count(event.Data, newline) + 1
+	capacity += len(event.Data) + (dataLines * 7)
+	for _, field := range event.Fields {
+		capacity += len(field.Name) + len(field.Value) + 3
+	}
+
+	buf = slices.Grow(buf, capacity)
+
+	id := stripChars(event.ID, "\r\n\x00")
+	if id != "" {
+		buf = append(buf, idPrefix...)
+		buf = append(buf, id...)
+		buf = append(buf, newline...)
+	} else if event.ResetID {
+		buf = append(buf, emptyID...)
+		buf = append(buf, newline...)
+	}
+
+	if name := stripChars(event.Name, "\r\n\x00"); name != "" {
+		buf = append(buf, eventPrefix...)
+		buf = append(buf, name...)
+		buf = append(buf, newline...)
+	}
 
-		if _, err := destination.Write(newline); err != nil {
-			return err
+	if event.Retry > 0 {
+		buf = append(buf, retryPrefix...)
+		buf = strconv.AppendInt(buf, event.Retry.Milliseconds(), 10)
+		buf = append(buf, newline...)
+	}
+
+	for _, field := range event.Fields {
+		if !validFieldName(field.Name) {
+			continue
 		}
+
+		buf = append(buf, field.Name...)
+		buf = append(buf, ": "...)
+		buf = append(buf, stripChars(field.Value, "\r\n")...)
+		buf = append(buf, newline...)
 	}
 
-	// Write data lines
-	for _, line := range bytes.Split(event.Data, []byte("\n")) {
+	// split on CR, LF, and CRLF alike, as the client will
+	data := event.Data
+	for {
+		end := bytes.IndexAny(data, "\r\n")
+
+		line := data
+		if end != -1 {
+			line = data[:end]
+		}
+
 		if len(line) == 0 {
-			if _, err := destination.Write(emptyData); err != nil {
-				return err
-			}
+			buf = append(buf, emptyData...)
 		} else {
-			if _, err := destination.Write(dataPrefix); err != nil {
-				return err
-			}
-			if _, err := destination.Write(line); err != nil {
-				return err
-			}
+			buf = append(buf, dataPrefix...)
+			buf = append(buf, line...)
+		}
+		buf = append(buf, newline...)
+
+		if end == -1 {
+			break
 		}
 
-		if _, err := destination.Write(newline); err != nil {
-			return err
+		if data[end] == '\r' && end+1 < len(data) && data[end+1] == '\n' {
+			end++
 		}
+
+		data = data[end+1:]
 	}
 
-	// Final newline
-	_, err := destination.Write(newline)
-	return err
+	return append(buf, newline...)
+}
+
+func validFieldName(name string) bool {
+	switch name {
+	case "", "id", "event", "data", "retry":
+		return false
+	}
+
+	return !strings.ContainsAny(name, ":\r\n")
+}
+
+// stripChars removes any of the given characters from s, without allocating
+// if there are none.
+func stripChars(s string, chars string) string {
+	if !strings.ContainsAny(s, chars) {
+		return s
+	}
+
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(chars, r) {
+			return -1
+		}
+
+		return r
+	}, s)
 }
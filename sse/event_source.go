@@ -1,10 +1,17 @@
 package sse
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"iter"
+	"mime"
 	"net/http"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -27,19 +34,84 @@ import (
 //
 // If an EOF is received, Next() returns io.EOF, and subsequent calls to Next()
 // will return early. To read new events, Connect() must be called.
+//
+// Successful responses must have a Content-Type of text/event-stream, and
+// must not specify a charset other than UTF-8; otherwise, a ContentTypeError
+// is returned. Config.AllowAnyContentType disables this check for servers
+// which do not set it properly.
+//
+// If the server responds with 204 No Content, the source is closed and
+// ErrNoContent is returned, as the spec uses it to tell clients to stop
+// reconnecting.
+//
+// ConnectContext() and NextContext() additionally accept a context which, when
+// cancelled, aborts the in-flight request, read, or retry wait and returns the
+// context's error. Unlike Close(), this does not close the source; the next
+// call to NextContext() reconnects, resuming from the last event ID.
+//
+// Like the browser's EventSource, the source reports its ReadyState(), and
+// Config can provide callbacks which are invoked when a connection opens,
+// when it fails and will be retried, and when the source closes.
 type EventSource struct {
 	client        Doer
 	createRequest func() *http.Request
 
 	currentReadCloser *ReadCloser
+	cancelConnection  context.CancelFunc
 	lastEventID       string
 	lock              sync.Mutex
 
 	closeOnce *sync.Once
 	closed    chan struct{}
 
-	retryInterval time.Duration
-	maxRetries    uint16
+	retryPolicy     RetryPolicy
+	serverRetryMode ServerRetryMode
+	serverRetry     time.Duration
+	lastRetryDelay  time.Duration
+
+	allowAnyContentType bool
+	readerOptions       ReaderOptions
+	idleTimeout         time.Duration
+
+	idStore   LastEventIDStore
+	manualAck bool
+	savedID   string
+
+	readyState atomic.Int32
+	onOpen     func(*http.Response)
+	onError    func(error, time.Duration)
+	onClose    func(error)
+
+	listenersLock sync.RWMutex
+	listeners     map[string][]*eventListener
+}
+
+// ReadyState is the state of an EventSource's connection.
+type ReadyState int32
+
+const (
+	// Connecting means the source is connecting, or waiting to reconnect.
+	Connecting ReadyState = iota
+
+	// Open means the source is connected and reading events.
+	Open
+
+	// Closed means the source has been closed, the stream has ended, or the
+	// connection failed and will not be retried.
+	Closed
+)
+
+func (state ReadyState) String() string {
+	switch state {
+	case Connecting:
+		return "connecting"
+	case Open:
+		return "open"
+	case Closed:
+		return "closed"
+	default:
+		return fmt.Sprintf("ReadyState(%d)", int32(state))
+	}
 }
 
 type Doer interface {
@@ -54,6 +126,21 @@ func (err BadResponseError) Error() string {
 	return fmt.Sprintf("bad response from event source: %s", err.Response.Status)
 }
 
+type ContentTypeError struct {
+	Response *http.Response
+}
+
+func (err ContentTypeError) Error() string {
+	return fmt.Sprintf("bad content type from event source: %q", err.Response.Header.Get("Content-Type"))
+}
+
+// RetryParams configures a constant retry policy. It is used when
+// Config.RetryPolicy is not set.
+//
+// Unlike ConstantBackoff, MaxRetries only limits consecutive attempts which
+// failed without a response, e.g. because the server could not be reached.
+// Retryable error responses, such as 503 Service Unavailable, are retried
+// indefinitely.
 type RetryParams struct {
 	RetryInterval time.Duration
 	MaxRetries    uint16
@@ -63,17 +150,103 @@ type Config struct {
 	Client         Doer
 	RetryParams    RetryParams
 	RequestCreator func() *http.Request
+
+	// RetryPolicy decides how long to wait before reconnecting, and when to
+	// give up. Defaults to a constant backoff configured by RetryParams.
+	RetryPolicy RetryPolicy
+
+	// ServerRetry determines how a retry interval sent by the server is
+	// combined with RetryPolicy. Defaults to ServerRetryOverride.
+	ServerRetry ServerRetryMode
+
+	// AllowAnyContentType accepts successful responses regardless of their
+	// Content-Type, for legacy servers which do not send text/event-stream.
+	AllowAnyContentType bool
+
+	// ReaderOptions configures how the stream is parsed. If the stream
+	// exceeds its limits or is rejected as invalid UTF-8, the source is
+	// closed with the error rather than reconnecting, as the server would
+	// most likely send the same again.
+	ReaderOptions ReaderOptions
+
+	// IdleTimeout is how long to wait for anything to arrive on the stream,
+	// including comments, before dropping the connection and reconnecting as
+	// if it had been interrupted, with ErrIdleTimeout. This detects
+	// connections which have silently stalled, e.g. behind a proxy, so the
+	// server should send comments as heartbeats more often than this. Zero
+	// means no timeout.
+	IdleTimeout time.Duration
+
+	// LastEventIDStore persists the ID of the last event processed, so that
+	// a restarted process resumes the stream from it. The saved ID is loaded
+	// when connecting, and by default is saved whenever an event is read.
+	LastEventIDStore LastEventIDStore
+
+	// ManualAck saves an event's ID to LastEventIDStore only once it is
+	// passed to EventSource.Ack, so that an event which was read but not
+	// fully processed before a crash is delivered again after a restart.
+	ManualAck bool
+
+	// OnOpen is called with the response whenever a connection is
+	// established.
+	OnOpen func(*http.Response)
+
+	// OnError is called whenever a connection fails or is interrupted and
+	// will be retried after the given delay.
+	OnError func(err error, retryDelay time.Duration)
+
+	// OnClose is called when the source becomes Closed, with the error which
+	// ended it, or nil if Close() was called.
+	OnClose func(err error)
 }
 
 func (c *Config) Connect() (*EventSource, error) {
+	return c.ConnectContext(context.Background())
+}
+
+func (c *Config) ConnectContext(ctx context.Context) (*EventSource, error) {
 	client := c.Client
 	if client == nil {
 		client = http.DefaultClient
 	}
-	source := createEventSource(client, c.RetryParams, c.RequestCreator)
+	retryPolicy := c.RetryPolicy
+	if retryPolicy == nil {
+		retryPolicy = retryParamsBackoff{
+			ConstantBackoff{
+				Interval:   c.RetryParams.RetryInterval,
+				MaxRetries: int(c.RetryParams.MaxRetries),
+			},
+		}
+	}
+
+	var lastEventID string
+	if c.LastEventIDStore != nil {
+		var err error
+		lastEventID, err = c.LastEventIDStore.Load()
+		if err != nil {
+			return nil, err
+		}
+	}
 
-	readCloser, err := source.establishConnection()
+	source := createEventSource(client, retryPolicy, c.RequestCreator)
+	source.lastEventID = lastEventID
+	source.savedID = lastEventID
+	source.idStore = c.LastEventIDStore
+	source.manualAck = c.ManualAck
+	source.serverRetryMode = c.ServerRetry
+	source.allowAnyContentType = c.AllowAnyContentType
+	source.readerOptions = c.ReaderOptions
+	source.idleTimeout = c.IdleTimeout
+	source.onOpen = c.OnOpen
+	source.onError = c.OnError
+	source.onClose = c.OnClose
+
+	readCloser, err := source.establishConnection(ctx)
 	if err != nil {
+		if err != ctx.Err() {
+			source.closedBy(err)
+		}
+
 		return nil, err
 	}
 
@@ -83,28 +256,31 @@ func (c *Config) Connect() (*EventSource, error) {
 }
 
 func NewEventSource(client Doer, defaultRetryInterval time.Duration, requestCreator func() *http.Request) *EventSource {
-	retryParams := RetryParams{
-		RetryInterval: defaultRetryInterval,
+	retryPolicy := ConstantBackoff{
+		Interval: defaultRetryInterval,
 	}
-	return createEventSource(client, retryParams, requestCreator)
+	return createEventSource(client, retryPolicy, requestCreator)
 }
 
-func createEventSource(client Doer, retryParams RetryParams, requestCreator func() *http.Request) *EventSource {
+func createEventSource(client Doer, retryPolicy RetryPolicy, requestCreator func() *http.Request) *EventSource {
 	return &EventSource{
 		client:        client,
 		createRequest: requestCreator,
 
-		closeOnce:     new(sync.Once),
-		closed:        make(chan struct{}),
-		retryInterval: retryParams.RetryInterval,
-		maxRetries:    retryParams.MaxRetries,
+		closeOnce:   new(sync.Once),
+		closed:      make(chan struct{}),
+		retryPolicy: retryPolicy,
 	}
 }
 
 func Connect(client Doer, defaultRetryInterval time.Duration, requestCreator func() *http.Request) (*EventSource, error) {
+	return ConnectContext(context.Background(), client, defaultRetryInterval, requestCreator)
+}
+
+func ConnectContext(ctx context.Context, client Doer, defaultRetryInterval time.Duration, requestCreator func() *http.Request) (*EventSource, error) {
 	source := NewEventSource(client, defaultRetryInterval, requestCreator)
 
-	readCloser, err := source.establishConnection()
+	readCloser, err := source.establishConnection(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -115,6 +291,56 @@ func Connect(client Doer, defaultRetryInterval time.Duration, requestCreator fun
 }
 
 func (source *EventSource) Next() (Event, error) {
+	return source.NextContext(context.Background())
+}
+
+func (source *EventSource) NextContext(ctx context.Context) (Event, error) {
+	event, err := source.next(ctx)
+	if err != nil && err != ErrSourceClosed && err != ctx.Err() {
+		if err != io.EOF {
+			// the connection failed and will not be retried
+			source.close()
+		}
+
+		source.closedBy(err)
+	}
+
+	return event, err
+}
+
+// Events returns an iterator over the events read from the source,
+// reconnecting as NextContext() does. Iteration stops when the stream ends;
+// any other error, including the context's, is yielded and ends the
+// iteration. The source is closed once iteration stops, including when the
+// loop breaks early.
+func (source *EventSource) Events(ctx context.Context) iter.Seq2[Event, error] {
+	return func(yield func(Event, error) bool) {
+		defer source.Close()
+
+		for {
+			event, err := source.NextContext(ctx)
+			if err == io.EOF {
+				return
+			}
+
+			if err != nil {
+				yield(Event{}, err)
+				return
+			}
+
+			if !yield(event, nil) {
+				return
+			}
+		}
+	}
+}
+
+// ReadyState returns the current state of the source's connection.
+func (source *EventSource) ReadyState() ReadyState {
+	return ReadyState(source.readyState.Load())
+}
+
+func (source *EventSource) next(ctx context.Context) (Event, error) {
 	select {
 	case <-source.closed:
 		return Event{}, ErrSourceClosed
@@ -122,17 +348,42 @@ func (source *EventSource) Next() (Event, error) {
 	}
 
 	for {
-		readCloser, err := source.ensureReadCloser()
+		if err := ctx.Err(); err != nil {
+			return Event{}, err
+		}
+
+		readCloser, err := source.ensureReadCloser(ctx)
 		if err != nil {
 			return Event{}, err
 		}
 
+		// abort the read if the context is cancelled
+		stopAborting := context.AfterFunc(ctx, func() {
+			readCloser.Close()
+		})
+
 		event, err := readCloser.Next()
-		if err == nil {
-			source.lastEventID = event.ID
 
+		source.lastEventID = readCloser.LastEventID()
+
+		if !stopAborting() {
+			// the connection was (or is being) closed by the context; drop it so
+			// that the next call reconnects
+			source.discardReadCloser(readCloser)
+
+			if err != nil {
+				return Event{}, ctx.Err()
+			}
+		}
+
+		if err == nil {
 			if event.Retry != 0 {
-				source.retryInterval = event.Retry
+				source.serverRetry = event.Retry
+			}
+
+			if err := source.saveLastEventID(); err != nil {
+				source.close()
+				return Event{}, err
 			}
 
 			return event, nil
@@ -142,17 +393,54 @@ func (source *EventSource) Next() (Event, error) {
 			return Event{}, err
 		}
 
+		var invalidUTF8 InvalidUTF8Error
+		if errors.Is(err, ErrEventTooLarge) || errors.As(err, &invalidUTF8) {
+			return Event{}, err
+		}
+
 		readCloser.Close()
 
-		if err := source.waitForRetry(); err != nil {
+		if err := source.waitForRetry(ctx, RetryAttempt{Err: err}); err != nil {
 			return Event{}, err
 		}
 	}
+}
+
+// Ack saves the event's ID to the configured LastEventIDStore, marking it and
+// every event before it as processed. It is only needed with
+// Config.ManualAck; otherwise IDs are saved as events are read.
+func (source *EventSource) Ack(event Event) error {
+	if source.idStore == nil {
+		return nil
+	}
 
-	panic("unreachable")
+	return source.idStore.Save(event.ID)
+}
+
+func (source *EventSource) saveLastEventID() error {
+	if source.idStore == nil || source.manualAck || source.lastEventID == source.savedID {
+		return nil
+	}
+
+	err := source.idStore.Save(source.lastEventID)
+	if err != nil {
+		return fmt.Errorf("save last event ID: %w", err)
+	}
+
+	source.savedID = source.lastEventID
+
+	return nil
 }
 
 func (source *EventSource) Close() error {
+	err := source.close()
+
+	source.closedBy(nil)
+
+	return err
+}
+
+func (source *EventSource) close() error {
 	source.lock.Lock()
 	defer source.lock.Unlock()
 
@@ -160,6 +448,12 @@ func (source *EventSource) Close() error {
 		close(source.closed)
 	})
 
+	if source.cancelConnection != nil {
+		// abort any request in flight
+		source.cancelConnection()
+		source.cancelConnection = nil
+	}
+
 	if source.currentReadCloser != nil {
 		err := source.currentReadCloser.Close()
 		if err != nil {
@@ -172,13 +466,13 @@ func (source *EventSource) Close() error {
 	return nil
 }
 
-func (source *EventSource) ensureReadCloser() (*ReadCloser, error) {
+func (source *EventSource) ensureReadCloser(ctx context.Context) (*ReadCloser, error) {
 	source.lock.Lock()
 
 	if source.currentReadCloser == nil {
 		source.lock.Unlock()
 
-		newReadCloser, err := source.establishConnection()
+		newReadCloser, err := source.establishConnection(ctx)
 		if err != nil {
 			return nil, err
 		}
@@ -203,8 +497,22 @@ func (source *EventSource) ensureReadCloser() (*ReadCloser, error) {
 	return readCloser, nil
 }
 
-func (source *EventSource) establishConnection() (*ReadCloser, error) {
-	var connectionRetries uint16
+func (source *EventSource) discardReadCloser(readCloser *ReadCloser) {
+	source.lock.Lock()
+	defer source.lock.Unlock()
+
+	readCloser.Close()
+
+	if source.currentReadCloser == readCloser {
+		source.currentReadCloser = nil
+		source.readyState.CompareAndSwap(int32(Open), int32(Connecting))
+	}
+}
+
+func (source *EventSource) establishConnection(ctx context.Context) (*ReadCloser, error) {
+	// failedRequests counts only the attempts which failed without a
+	// response, for the policy built from RetryParams
+	var failedAttempts, failedRequests int
 	for {
 		req := source.createRequest()
 
@@ -216,13 +524,26 @@ func (source *EventSource) establishConnection() (*ReadCloser, error) {
 			req.Header.Set("Last-Event-ID", source.lastEventID)
 		}
 
-		res, err := source.client.Do(req)
+		res, cancel, err := source.do(ctx, req)
 		if err != nil {
-			connectionRetries++
-			if !source.shouldRetry(connectionRetries) {
-				return nil, err
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+
+			select {
+			case <-source.closed:
+				return nil, ErrSourceClosed
+			default:
 			}
-			err := source.waitForRetry()
+
+			failedAttempts++
+			failedRequests++
+
+			err := source.waitForRetry(ctx, RetryAttempt{
+				Attempt:        failedAttempts,
+				Err:            err,
+				failedRequests: failedRequests,
+			})
 			if err != nil {
 				return nil, err
 			}
@@ -232,16 +553,72 @@ func (source *EventSource) establishConnection() (*ReadCloser, error) {
 
 		switch res.StatusCode {
 		case http.StatusOK:
-			return NewReadCloser(res.Body), nil
+			if !source.allowAnyContentType && !isEventStream(res.Header.Get("Content-Type")) {
+				res.Body.Close()
+				cancel()
+
+				return nil, ContentTypeError{
+					Response: res,
+				}
+			}
+
+			// carry the last event ID over to events on the new connection
+			options := source.readerOptions
+			options.LastEventID = source.lastEventID
+
+			var body io.ReadCloser = res.Body
+			if source.idleTimeout > 0 {
+				body = newIdleReader(body, source.idleTimeout, cancel)
+			}
+
+			readCloser := NewReadCloserWithOptions(body, options)
+			readCloser.closeSource = func() error {
+				defer cancel()
+				return body.Close()
+			}
+
+			source.lastRetryDelay = 0
+
+			source.transition(Open)
+
+			if source.onOpen != nil {
+				source.onOpen(res)
+			}
+
+			return readCloser, nil
+
+		// the server wants us to stop reconnecting
+		case http.StatusNoContent:
+			res.Body.Close()
+			cancel()
+
+			source.close()
+
+			return nil, ErrNoContent
 
 		// reestablish the connection
 		case http.StatusInternalServerError,
 			http.StatusBadGateway,
 			http.StatusServiceUnavailable,
-			http.StatusGatewayTimeout:
+			http.StatusGatewayTimeout,
+			http.StatusTooManyRequests:
 			res.Body.Close()
+			cancel()
 
-			err := source.waitForRetry()
+			failedAttempts++
+
+			var retryAfter time.Duration
+			if res.StatusCode == http.StatusServiceUnavailable || res.StatusCode == http.StatusTooManyRequests {
+				retryAfter, _ = parseRetryAfter(res.Header.Get("Retry-After"), time.Now())
+			}
+
+			err := source.waitForRetry(ctx, RetryAttempt{
+				Attempt:        failedAttempts,
+				Err:            BadResponseError{Response: res},
+				StatusCode:     res.StatusCode,
+				RetryAfter:     retryAfter,
+				failedRequests: failedRequests,
+			})
 			if err != nil {
 				return nil, err
 			}
@@ -251,6 +628,7 @@ func (source *EventSource) establishConnection() (*ReadCloser, error) {
 		// fail the connection
 		default:
 			res.Body.Close()
+			cancel()
 
 			return nil, BadResponseError{
 				Response: res,
@@ -259,20 +637,150 @@ func (source *EventSource) establishConnection() (*ReadCloser, error) {
 	}
 }
 
-func (source *EventSource) waitForRetry() error {
+// transition changes the ready state, unless the source has been closed.
+func (source *EventSource) transition(state ReadyState) {
+	source.lock.Lock()
+	defer source.lock.Unlock()
+
+	select {
+	case <-source.closed:
+	default:
+		source.readyState.Store(int32(state))
+	}
+}
+
+// closedBy transitions the source to Closed, calling OnClose if it was not
+// already closed.
+func (source *EventSource) closedBy(err error) {
+	if ReadyState(source.readyState.Swap(int32(Closed))) == Closed {
+		return
+	}
+
+	if source.onClose != nil {
+		source.onClose(err)
+	}
+}
+
+// do sends the request with a context which carries the values of ctx and is
+// cancelled if ctx is cancelled before a response is received, or if the
+// source is closed. Once a response is received, the connection outlives ctx,
+// so that it can be read by later calls.
+func (source *EventSource) do(ctx context.Context, req *http.Request) (*http.Response, context.CancelFunc, error) {
+	connCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+
+	source.lock.Lock()
+	select {
+	case <-source.closed:
+		source.lock.Unlock()
+		cancel()
+		return nil, nil, ErrSourceClosed
+	default:
+		source.cancelConnection = cancel
+	}
+	source.lock.Unlock()
+
+	stop := context.AfterFunc(ctx, cancel)
+	res, err := source.client.Do(req.WithContext(connCtx))
+	if !stop() || err != nil {
+		if err == nil {
+			res.Body.Close()
+			err = ctx.Err()
+		}
+
+		cancel()
+		return nil, nil, err
+	}
+
+	return res, cancel, nil
+}
+
+// waitForRetry waits for the delay before the given attempt, returning the
+// attempt's error if the retry policy gives up.
+func (source *EventSource) waitForRetry(ctx context.Context, attempt RetryAttempt) error {
 	source.lock.Lock()
 	source.currentReadCloser = nil
 	source.lock.Unlock()
 
+	delay, retry := source.retryDelay(attempt)
+	if !retry {
+		return attempt.Err
+	}
+
+	source.transition(Connecting)
+
+	if source.onError != nil {
+		source.onError(attempt.Err, delay)
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
 	select {
-	case <-time.After(source.retryInterval):
+	case <-timer.C:
 		return nil
 	case <-source.closed:
 		return ErrSourceClosed
+	case <-ctx.Done():
+		return ctx.Err()
 	}
 }
 
-func (source *EventSource) shouldRetry(retries uint16) bool {
-	return source.maxRetries == 0 ||
-		(source.maxRetries > 0 && retries <= source.maxRetries)
+func (source *EventSource) retryDelay(attempt RetryAttempt) (time.Duration, bool) {
+	attempt.Previous = source.lastRetryDelay
+
+	delay, retry := source.retryPolicy.NextDelay(attempt)
+	if !retry {
+		return 0, false
+	}
+
+	if attempt.RetryAfter != 0 {
+		delay = attempt.RetryAfter
+	} else if source.serverRetry != 0 {
+		switch source.serverRetryMode {
+		case ServerRetryOverride:
+			delay = source.serverRetry
+		case ServerRetryFloor:
+			delay = max(delay, source.serverRetry)
+		}
+	}
+
+	source.lastRetryDelay = delay
+
+	return delay, true
+}
+
+// isEventStream checks that a Content-Type is text/event-stream, with either
+// no charset or UTF-8.
+func isEventStream(contentType string) bool {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType != "text/event-stream" {
+		return false
+	}
+
+	charset, found := params["charset"]
+
+	return !found || strings.EqualFold(charset, "utf-8")
+}
+
+// parseRetryAfter parses a Retry-After header given either as a number of
+// seconds or as an HTTP date.
+func parseRetryAfter(header string, now time.Time) (time.Duration, bool) {
+	if header == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.ParseInt(header, 10, 64); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	date, err := http.ParseTime(header)
+	if err != nil {
+		return 0, false
+	}
+
+	return max(date.Sub(now), 0), true
 }
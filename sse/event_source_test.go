@@ -1,6 +1,7 @@
 package sse_test
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -37,6 +38,63 @@ func (f *failingRoundTripper) RoundTrip(req *http.Request) (*http.Response, erro
 	return nil, errors.New("failed to connect")
 }
 
+type contextKey struct{}
+
+type recordingDoer struct {
+	contexts chan context.Context
+}
+
+func (doer *recordingDoer) Do(req *http.Request) (*http.Response, error) {
+	doer.contexts <- req.Context()
+	return http.DefaultClient.Do(req)
+}
+
+// stallingDoer responds to each request with a stream which sends the next of
+// its events and then stalls, regardless of the request's context.
+type stallingDoer struct {
+	events   []Event
+	requests int
+}
+
+func (doer *stallingDoer) Do(req *http.Request) (*http.Response, error) {
+	reader, writer := io.Pipe()
+
+	if doer.requests < len(doer.events) {
+		go doer.events[doer.requests].Write(writer)
+	}
+
+	doer.requests++
+
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"text/event-stream"}},
+		Body:       reader,
+	}, nil
+}
+
+type recordingRetryPolicy struct {
+	attempts chan RetryAttempt
+	delay    time.Duration
+}
+
+func (policy recordingRetryPolicy) NextDelay(attempt RetryAttempt) (time.Duration, bool) {
+	policy.attempts <- attempt
+	return policy.delay, attempt.Attempt < 3
+}
+
+type failingIDStore struct {
+	loadErr error
+	saveErr error
+}
+
+func (store failingIDStore) Load() (string, error) {
+	return "", store.loadErr
+}
+
+func (store failingIDStore) Save(string) error {
+	return store.saveErr
+}
+
 var _ = Describe("EventSource", func() {
 	var (
 		server *ghttp.Server
@@ -150,6 +208,48 @@ var _ = Describe("EventSource", func() {
 		})
 	})
 
+	Context("when the stream sets an id without dispatching an event", func() {
+		BeforeEach(func() {
+			server.AppendHandlers(
+				func(w http.ResponseWriter, r *http.Request) {
+					w.Header().Add("Content-Type", "text/event-stream; charset=utf-8")
+					w.WriteHeader(http.StatusOK)
+
+					fmt.Fprint(w, "id: 1\ndata: hello\n\nid: 2\n\n")
+					w.(http.Flusher).Flush()
+
+					<-r.Context().Done()
+				},
+				ghttp.CombineHandlers(
+					ghttp.VerifyHeaderKV("Last-Event-ID", "2"),
+					func(w http.ResponseWriter, r *http.Request) {
+						w.Header().Add("Content-Type", "text/event-stream; charset=utf-8")
+						w.WriteHeader(http.StatusOK)
+
+						fmt.Fprint(w, "data: welcome back\n\n")
+					},
+				),
+			)
+		})
+
+		It("reconnects from that id and carries it over to the new connection", func() {
+			Ω(source.Next()).Should(Equal(Event{
+				ID:   "1",
+				Data: []byte("hello"),
+			}))
+
+			time.AfterFunc(100*time.Millisecond, server.CloseClientConnections)
+
+			Ω(source.Next()).Should(Equal(Event{
+				ID:   "2",
+				Data: []byte("welcome back"),
+			}))
+
+			_, err := source.Next()
+			Ω(err).Should(Equal(io.EOF))
+		})
+	})
+
 	Context("when reconnecting continuously fails", func() {
 		var retryTimes <-chan time.Time
 
@@ -299,6 +399,179 @@ var _ = Describe("EventSource", func() {
 		})
 	})
 
+	Describe("validating the content type", func() {
+		var contentType string
+
+		JustBeforeEach(func() {
+			server.AppendHandlers(
+				func(w http.ResponseWriter, r *http.Request) {
+					w.Header().Set("Content-Type", contentType)
+					w.WriteHeader(http.StatusOK)
+
+					Event{
+						ID:   "1",
+						Data: []byte("hello"),
+					}.Write(w)
+				},
+			)
+		})
+
+		for _, valid := range []string{
+			"text/event-stream",
+			"text/event-stream;charset=UTF-8",
+			"Text/Event-Stream; charset=utf-8; foo=bar",
+		} {
+			valid := valid
+
+			Context(fmt.Sprintf("when the server responds with %q", valid), func() {
+				BeforeEach(func() {
+					contentType = valid
+				})
+
+				It("reads the stream", func() {
+					Ω(source.Next()).Should(Equal(Event{
+						ID:   "1",
+						Data: []byte("hello"),
+					}))
+				})
+			})
+		}
+
+		for _, invalid := range []string{
+			"text/html; charset=utf-8",
+			"text/event-stream; charset=iso-8859-1",
+			"",
+		} {
+			invalid := invalid
+
+			Context(fmt.Sprintf("when the server responds with %q", invalid), func() {
+				BeforeEach(func() {
+					contentType = invalid
+				})
+
+				It("returns a ContentTypeError without retrying", func() {
+					_, err := source.Next()
+					Ω(err).Should(BeAssignableToTypeOf(ContentTypeError{}))
+					Ω(err.(ContentTypeError).Response.StatusCode).Should(Equal(http.StatusOK))
+
+					Ω(server.ReceivedRequests()).Should(HaveLen(1))
+				})
+			})
+		}
+
+		Context("when any content type is allowed", func() {
+			BeforeEach(func() {
+				contentType = "text/plain"
+			})
+
+			It("reads the stream", func() {
+				config := Config{
+					AllowAnyContentType: true,
+					RequestCreator: func() *http.Request {
+						request, err := http.NewRequest("GET", server.URL(), nil)
+						Ω(err).ShouldNot(HaveOccurred())
+						return request
+					},
+				}
+
+				src, err := config.Connect()
+				Ω(err).ShouldNot(HaveOccurred())
+
+				Ω(src.Next()).Should(Equal(Event{
+					ID:   "1",
+					Data: []byte("hello"),
+				}))
+			})
+		})
+	})
+
+	Context("when the server returns 204", func() {
+		BeforeEach(func() {
+			server.AppendHandlers(
+				ghttp.RespondWith(http.StatusNoContent, ""),
+			)
+		})
+
+		It("returns ErrNoContent and closes the source", func() {
+			_, err := source.Next()
+			Ω(err).Should(Equal(ErrNoContent))
+
+			_, err = source.Next()
+			Ω(err).Should(Equal(ErrSourceClosed))
+
+			Ω(server.ReceivedRequests()).Should(HaveLen(1))
+		})
+	})
+
+	for _, throttlingStatus := range []int{
+		http.StatusServiceUnavailable,
+		http.StatusTooManyRequests,
+	} {
+		status := throttlingStatus
+
+		Context(fmt.Sprintf("when the server returns %d with Retry-After", status), func() {
+			var retryAfter string
+
+			BeforeEach(func() {
+				source = NewEventSource(http.DefaultClient, time.Hour, func() *http.Request {
+					request, err := http.NewRequest("GET", server.URL(), nil)
+					Ω(err).ShouldNot(HaveOccurred())
+					return request
+				})
+			})
+
+			JustBeforeEach(func() {
+				server.AppendHandlers(
+					ghttp.RespondWith(status, "", http.Header{"Retry-After": {retryAfter}}),
+					func(w http.ResponseWriter, r *http.Request) {
+						w.Header().Add("Content-Type", "text/event-stream; charset=utf-8")
+						w.WriteHeader(http.StatusOK)
+
+						Event{
+							ID:   "1",
+							Data: []byte("you made it!"),
+						}.Write(w)
+					},
+				)
+			})
+
+			Context("given in seconds", func() {
+				BeforeEach(func() {
+					retryAfter = "1"
+				})
+
+				It("waits that long before reconnecting", func() {
+					start := time.Now()
+
+					Ω(source.Next()).Should(Equal(Event{
+						ID:   "1",
+						Data: []byte("you made it!"),
+					}))
+
+					Ω(time.Since(start)).Should(BeNumerically("~", time.Second, 250*time.Millisecond))
+				})
+			})
+
+			Context("given as a date", func() {
+				BeforeEach(func() {
+					retryAfter = time.Now().Add(2 * time.Second).UTC().Format(http.TimeFormat)
+				})
+
+				It("waits until then before reconnecting", func() {
+					start := time.Now()
+
+					Ω(source.Next()).Should(Equal(Event{
+						ID:   "1",
+						Data: []byte("you made it!"),
+					}))
+
+					Ω(time.Since(start)).Should(BeNumerically(">", time.Second))
+					Ω(time.Since(start)).Should(BeNumerically("<", 2250*time.Millisecond))
+				})
+			})
+		})
+	}
+
 	Describe("handling errors while reading events", func() {
 		var eventChan chan Event
 		var errChan chan error
@@ -475,6 +748,52 @@ var _ = Describe("EventSource", func() {
 			})
 		})
 
+		Context("when event source responds with retryable errors", func() {
+			BeforeEach(func() {
+				retryParams = RetryParams{
+					MaxRetries:    1,
+					RetryInterval: 10 * time.Millisecond,
+				}
+
+				server.AppendHandlers(
+					ghttp.RespondWith(http.StatusServiceUnavailable, nil),
+					ghttp.RespondWith(http.StatusBadGateway, nil),
+					ghttp.RespondWith(http.StatusInternalServerError, nil),
+					func(w http.ResponseWriter, r *http.Request) {
+						w.Header().Add("Content-Type", "text/event-stream; charset=utf-8")
+						w.WriteHeader(http.StatusOK)
+
+						Event{
+							ID:   "1",
+							Data: []byte("finally"),
+						}.Write(w)
+					},
+				)
+			})
+
+			It("retries them regardless of max retries", func() {
+				config := Config{
+					RetryParams: retryParams,
+					RequestCreator: func() *http.Request {
+						request, err := http.NewRequest("GET", server.URL(), nil)
+						Ω(err).ShouldNot(HaveOccurred())
+						return request
+					},
+				}
+
+				src, err := config.Connect()
+				Ω(err).ShouldNot(HaveOccurred())
+
+				defer src.Close()
+
+				Ω(src.Next()).Should(Equal(Event{
+					ID:   "1",
+					Data: []byte("finally"),
+				}))
+				Ω(server.ReceivedRequests()).Should(HaveLen(4))
+			})
+		})
+
 		Context("when event source becomes unavailable after initial connection", func() {
 			Context("and stays unavailable for more than max retries", func() {
 				var (
@@ -645,11 +964,859 @@ var _ = Describe("EventSource", func() {
 		})
 	})
 
+	Context("with a retry policy", func() {
+		var (
+			attempts chan RetryAttempt
+			config   Config
+		)
+
+		BeforeEach(func() {
+			attempts = make(chan RetryAttempt, 10)
+
+			config = Config{
+				RetryPolicy: recordingRetryPolicy{
+					attempts: attempts,
+					delay:    10 * time.Millisecond,
+				},
+				RequestCreator: func() *http.Request {
+					request, err := http.NewRequest("GET", server.URL(), nil)
+					Ω(err).ShouldNot(HaveOccurred())
+					return request
+				},
+			}
+
+			server.AppendHandlers(
+				func(w http.ResponseWriter, r *http.Request) {
+					w.Header().Add("Content-Type", "text/event-stream; charset=utf-8")
+					w.WriteHeader(http.StatusOK)
+
+					Event{
+						ID:    "1",
+						Data:  []byte("hello"),
+						Retry: 200 * time.Millisecond,
+					}.Write(w)
+
+					w.(http.Flusher).Flush()
+
+					server.CloseClientConnections()
+				},
+				ghttp.RespondWith(http.StatusBadGateway, ""),
+				ghttp.RespondWith(http.StatusBadGateway, ""),
+				ghttp.RespondWith(http.StatusBadGateway, ""),
+			)
+		})
+
+		It("consults the policy for each attempt until it gives up", func() {
+			src, err := config.Connect()
+			Ω(err).ShouldNot(HaveOccurred())
+
+			Ω(src.Next()).Should(Equal(Event{
+				ID:    "1",
+				Data:  []byte("hello"),
+				Retry: 200 * time.Millisecond,
+			}))
+
+			_, err = src.Next()
+			Ω(err).Should(BeAssignableToTypeOf(BadResponseError{}))
+
+			var attempt RetryAttempt
+
+			Ω(attempts).Should(Receive(&attempt))
+			Ω(attempt.Attempt).Should(Equal(0))
+			Ω(attempt.Err).Should(HaveOccurred())
+			Ω(attempt.Previous).Should(BeZero())
+
+			for i := 1; i <= 3; i++ {
+				Ω(attempts).Should(Receive(&attempt))
+				Ω(attempt.Attempt).Should(Equal(i))
+				Ω(attempt.StatusCode).Should(Equal(http.StatusBadGateway))
+				Ω(attempt.Previous).Should(Equal(200 * time.Millisecond))
+			}
+
+			Ω(src.ReadyState()).Should(Equal(Closed))
+
+			_, err = src.Next()
+			Ω(err).Should(Equal(ErrSourceClosed))
+			Ω(server.ReceivedRequests()).Should(HaveLen(4))
+		})
+
+		Context("when the server's retry interval is ignored", func() {
+			BeforeEach(func() {
+				config.ServerRetry = ServerRetryIgnore
+			})
+
+			It("waits the policy's delay", func() {
+				src, err := config.Connect()
+				Ω(err).ShouldNot(HaveOccurred())
+
+				_, err = src.Next()
+				Ω(err).ShouldNot(HaveOccurred())
+
+				_, err = src.Next()
+				Ω(err).Should(HaveOccurred())
+
+				var attempt RetryAttempt
+				Ω(attempts).Should(Receive(&attempt))
+				Ω(attempts).Should(Receive(&attempt))
+				Ω(attempt.Previous).Should(Equal(10 * time.Millisecond))
+			})
+		})
+	})
+
+	Describe("lifecycle", func() {
+		var (
+			config Config
+
+			opened     chan *http.Response
+			errored    chan time.Duration
+			closedWith chan error
+		)
+
+		BeforeEach(func() {
+			opened = make(chan *http.Response, 10)
+			errored = make(chan time.Duration, 10)
+			closedWith = make(chan error, 10)
+
+			config = Config{
+				RetryParams: RetryParams{RetryInterval: 10 * time.Millisecond},
+				RequestCreator: func() *http.Request {
+					request, err := http.NewRequest("GET", server.URL(), nil)
+					Ω(err).ShouldNot(HaveOccurred())
+					return request
+				},
+				OnOpen: func(res *http.Response) {
+					opened <- res
+				},
+				OnError: func(err error, retryDelay time.Duration) {
+					Ω(err).Should(HaveOccurred())
+					errored <- retryDelay
+				},
+				OnClose: func(err error) {
+					closedWith <- err
+				},
+			}
+		})
+
+		It("starts out connecting", func() {
+			Ω(source.ReadyState()).Should(Equal(Connecting))
+		})
+
+		Context("when the connection is interrupted", func() {
+			BeforeEach(func() {
+				server.AppendHandlers(
+					func(w http.ResponseWriter, r *http.Request) {
+						w.Header().Add("Content-Type", "text/event-stream; charset=utf-8")
+						w.WriteHeader(http.StatusOK)
+
+						Event{
+							ID:   "1",
+							Data: []byte("hello"),
+						}.Write(w)
+
+						w.(http.Flusher).Flush()
+
+						server.CloseClientConnections()
+					},
+					func(w http.ResponseWriter, r *http.Request) {
+						w.Header().Add("Content-Type", "text/event-stream; charset=utf-8")
+						w.WriteHeader(http.StatusOK)
+
+						Event{
+							ID:   "2",
+							Data: []byte("welcome back"),
+						}.Write(w)
+					},
+				)
+			})
+
+			It("reports each transition", func() {
+				src, err := config.Connect()
+				Ω(err).ShouldNot(HaveOccurred())
+
+				Ω(src.ReadyState()).Should(Equal(Open))
+
+				var res *http.Response
+				Ω(opened).Should(Receive(&res))
+				Ω(res.StatusCode).Should(Equal(http.StatusOK))
+
+				_, err = src.Next()
+				Ω(err).ShouldNot(HaveOccurred())
+
+				_, err = src.Next()
+				Ω(err).ShouldNot(HaveOccurred())
+
+				Ω(errored).Should(Receive(Equal(10 * time.Millisecond)))
+				Ω(opened).Should(Receive())
+				Ω(src.ReadyState()).Should(Equal(Open))
+
+				_, err = src.Next()
+				Ω(err).Should(Equal(io.EOF))
+
+				Ω(src.ReadyState()).Should(Equal(Closed))
+				Ω(closedWith).Should(Receive(Equal(io.EOF)))
+			})
+		})
+
+		Context("when the source is closed", func() {
+			BeforeEach(func() {
+				server.AppendHandlers(
+					func(w http.ResponseWriter, r *http.Request) {
+						w.Header().Add("Content-Type", "text/event-stream; charset=utf-8")
+						w.WriteHeader(http.StatusOK)
+					},
+				)
+			})
+
+			It("reports it once", func() {
+				src, err := config.Connect()
+				Ω(err).ShouldNot(HaveOccurred())
+
+				Ω(src.Close()).Should(Succeed())
+				Ω(src.Close()).Should(Succeed())
+
+				Ω(src.ReadyState()).Should(Equal(Closed))
+				Ω(closedWith).Should(Receive(BeNil()))
+				Ω(closedWith).ShouldNot(Receive())
+			})
+		})
+
+		Context("when the connection fails", func() {
+			BeforeEach(func() {
+				server.AppendHandlers(
+					ghttp.RespondWith(http.StatusNotFound, ""),
+				)
+			})
+
+			It("reports the error it closed with", func() {
+				_, err := config.Connect()
+				Ω(err).Should(HaveOccurred())
+
+				var closeErr error
+				Ω(closedWith).Should(Receive(&closeErr))
+				Ω(closeErr).Should(BeAssignableToTypeOf(BadResponseError{}))
+
+				Ω(errored).ShouldNot(Receive())
+			})
+		})
+
+		Context("when reconnecting fails", func() {
+			BeforeEach(func() {
+				server.AppendHandlers(
+					func(w http.ResponseWriter, r *http.Request) {
+						w.Header().Add("Content-Type", "text/event-stream; charset=utf-8")
+						w.WriteHeader(http.StatusOK)
+						w.(http.Flusher).Flush()
+
+						server.CloseClientConnections()
+					},
+					ghttp.RespondWith(http.StatusNotFound, ""),
+				)
+			})
+
+			It("closes the source, reporting it once", func() {
+				src, err := config.Connect()
+				Ω(err).ShouldNot(HaveOccurred())
+
+				_, err = src.Next()
+				Ω(err).Should(BeAssignableToTypeOf(BadResponseError{}))
+
+				Ω(src.ReadyState()).Should(Equal(Closed))
+				Ω(closedWith).Should(Receive(BeAssignableToTypeOf(BadResponseError{})))
+
+				_, err = src.Next()
+				Ω(err).Should(Equal(ErrSourceClosed))
+
+				Ω(src.ReadyState()).Should(Equal(Closed))
+				Ω(closedWith).ShouldNot(Receive())
+				Ω(server.ReceivedRequests()).Should(HaveLen(2))
+			})
+		})
+	})
+
+	Context("when the stream cannot be parsed", func() {
+		var (
+			config Config
+
+			reported   chan error
+			closedWith chan error
+		)
+
+		BeforeEach(func() {
+			reported = make(chan error, 10)
+			closedWith = make(chan error, 10)
+
+			config = Config{
+				RetryParams: RetryParams{RetryInterval: 10 * time.Millisecond},
+				RequestCreator: func() *http.Request {
+					request, err := http.NewRequest("GET", server.URL(), nil)
+					Ω(err).ShouldNot(HaveOccurred())
+					return request
+				},
+				ReaderOptions: ReaderOptions{
+					MaxLineLength: 10,
+					InvalidUTF8:   RejectInvalidUTF8,
+				},
+				OnError: func(err error, retryDelay time.Duration) {
+					reported <- err
+				},
+				OnClose: func(err error) {
+					closedWith <- err
+				},
+			}
+		})
+
+		for _, stream := range []string{
+			"data: much too long\n\n",
+			"data: \xff\n\n",
+		} {
+			Context(fmt.Sprintf("when the server sends %q", stream), func() {
+				BeforeEach(func() {
+					server.RouteToHandler("GET", "/", func(w http.ResponseWriter, r *http.Request) {
+						w.Header().Add("Content-Type", "text/event-stream; charset=utf-8")
+						w.WriteHeader(http.StatusOK)
+
+						fmt.Fprint(w, stream)
+					})
+				})
+
+				It("closes the source with the error without reconnecting", func() {
+					src, err := config.Connect()
+					Ω(err).ShouldNot(HaveOccurred())
+
+					_, err = src.Next()
+					Ω(err).Should(HaveOccurred())
+
+					Ω(closedWith).Should(Receive(Equal(err)))
+
+					_, err = src.Next()
+					Ω(err).Should(Equal(ErrSourceClosed))
+
+					Ω(reported).ShouldNot(Receive())
+					Ω(server.ReceivedRequests()).Should(HaveLen(1))
+				})
+			})
+		}
+	})
+
+	Describe("idle timeout", func() {
+		var (
+			config Config
+
+			reported chan error
+		)
+
+		BeforeEach(func() {
+			reported = make(chan error, 10)
+
+			config = Config{
+				RetryParams: RetryParams{RetryInterval: 10 * time.Millisecond},
+				RequestCreator: func() *http.Request {
+					request, err := http.NewRequest("GET", server.URL(), nil)
+					Ω(err).ShouldNot(HaveOccurred())
+					return request
+				},
+				IdleTimeout: 200 * time.Millisecond,
+				OnError: func(err error, retryDelay time.Duration) {
+					reported <- err
+				},
+			}
+		})
+
+		Context("when the stream stalls", func() {
+			BeforeEach(func() {
+				server.AppendHandlers(
+					func(w http.ResponseWriter, r *http.Request) {
+						w.Header().Add("Content-Type", "text/event-stream; charset=utf-8")
+						w.WriteHeader(http.StatusOK)
+
+						Event{
+							ID:   "1",
+							Data: []byte("hello"),
+						}.Write(w)
+
+						w.(http.Flusher).Flush()
+
+						<-r.Context().Done()
+					},
+					ghttp.CombineHandlers(
+						ghttp.VerifyHeaderKV("Last-Event-ID", "1"),
+						func(w http.ResponseWriter, r *http.Request) {
+							w.Header().Add("Content-Type", "text/event-stream; charset=utf-8")
+							w.WriteHeader(http.StatusOK)
+
+							Event{
+								ID:   "2",
+								Data: []byte("welcome back"),
+							}.Write(w)
+						},
+					),
+				)
+			})
+
+			It("reconnects, reporting ErrIdleTimeout", func() {
+				source, err := config.Connect()
+				Ω(err).ShouldNot(HaveOccurred())
+
+				defer source.Close()
+
+				Ω(source.Next()).Should(Equal(Event{
+					ID:   "1",
+					Data: []byte("hello"),
+				}))
+
+				started := time.Now()
+
+				Ω(source.Next()).Should(Equal(Event{
+					ID:   "2",
+					Data: []byte("welcome back"),
+				}))
+
+				Ω(time.Since(started)).Should(BeNumerically(">=", 200*time.Millisecond))
+				Ω(reported).Should(Receive(Equal(ErrIdleTimeout)))
+			})
+		})
+
+		Context("when the stream stalls without regard for the request's context", func() {
+			var doer *stallingDoer
+
+			BeforeEach(func() {
+				doer = &stallingDoer{
+					events: []Event{
+						{ID: "1", Data: []byte("hello")},
+						{ID: "2", Data: []byte("welcome back")},
+					},
+				}
+
+				config.Client = doer
+			})
+
+			It("closes the stream and reconnects", func() {
+				source, err := config.Connect()
+				Ω(err).ShouldNot(HaveOccurred())
+
+				defer source.Close()
+
+				Ω(source.Next()).Should(Equal(Event{ID: "1", Data: []byte("hello")}))
+				Ω(source.Next()).Should(Equal(Event{ID: "2", Data: []byte("welcome back")}))
+
+				Ω(reported).Should(Receive(MatchError(ErrIdleTimeout)))
+				Ω(doer.requests).Should(Equal(2))
+			})
+		})
+
+		Context("when the server sends heartbeats", func() {
+			BeforeEach(func() {
+				server.AppendHandlers(
+					func(w http.ResponseWriter, r *http.Request) {
+						w.Header().Add("Content-Type", "text/event-stream; charset=utf-8")
+						w.WriteHeader(http.StatusOK)
+						w.(http.Flusher).Flush()
+
+						for range 5 {
+							time.Sleep(100 * time.Millisecond)
+							fmt.Fprint(w, ": heartbeat\n")
+							w.(http.Flusher).Flush()
+						}
+
+						Event{
+							ID:   "1",
+							Data: []byte("hello"),
+						}.Write(w)
+					},
+				)
+			})
+
+			It("keeps the connection", func() {
+				source, err := config.Connect()
+				Ω(err).ShouldNot(HaveOccurred())
+
+				defer source.Close()
+
+				Ω(source.Next()).Should(Equal(Event{
+					ID:   "1",
+					Data: []byte("hello"),
+				}))
+
+				Ω(reported).ShouldNot(Receive())
+				Ω(server.ReceivedRequests()).Should(HaveLen(1))
+			})
+		})
+
+		Context("when the consumer is slower than the heartbeats", func() {
+			BeforeEach(func() {
+				server.AppendHandlers(
+					func(w http.ResponseWriter, r *http.Request) {
+						w.Header().Add("Content-Type", "text/event-stream; charset=utf-8")
+						w.WriteHeader(http.StatusOK)
+
+						Event{ID: "1", Data: []byte("hello")}.Write(w)
+						w.(http.Flusher).Flush()
+
+						for range 20 {
+							time.Sleep(50 * time.Millisecond)
+							fmt.Fprint(w, ": hb\n")
+							w.(http.Flusher).Flush()
+						}
+
+						Event{ID: "2", Data: []byte("hello again")}.Write(w)
+					},
+				)
+			})
+
+			It("does not count the time between reads", func() {
+				source, err := config.Connect()
+				Ω(err).ShouldNot(HaveOccurred())
+
+				defer source.Close()
+
+				Ω(source.Next()).Should(Equal(Event{ID: "1", Data: []byte("hello")}))
+
+				time.Sleep(500 * time.Millisecond)
+
+				Ω(source.Next()).Should(Equal(Event{ID: "2", Data: []byte("hello again")}))
+
+				Ω(reported).ShouldNot(Receive())
+				Ω(server.ReceivedRequests()).Should(HaveLen(1))
+			})
+		})
+	})
+
+	Describe("persisting the last event ID", func() {
+		var (
+			config Config
+			store  *MemoryLastEventIDStore
+		)
+
+		BeforeEach(func() {
+			store = &MemoryLastEventIDStore{}
+			Ω(store.Save("5")).Should(Succeed())
+
+			config = Config{
+				RequestCreator: func() *http.Request {
+					request, err := http.NewRequest("GET", server.URL(), nil)
+					Ω(err).ShouldNot(HaveOccurred())
+					return request
+				},
+				LastEventIDStore: store,
+			}
+
+			server.AppendHandlers(
+				ghttp.CombineHandlers(
+					ghttp.VerifyHeaderKV("Last-Event-ID", "5"),
+					func(w http.ResponseWriter, r *http.Request) {
+						w.Header().Add("Content-Type", "text/event-stream; charset=utf-8")
+						w.WriteHeader(http.StatusOK)
+
+						Event{ID: "6", Data: []byte("six")}.Write(w)
+						Event{ID: "7", Data: []byte("seven")}.Write(w)
+					},
+				),
+			)
+		})
+
+		It("resumes from the saved ID and saves each event's ID as it is read", func() {
+			source, err := config.Connect()
+			Ω(err).ShouldNot(HaveOccurred())
+
+			defer source.Close()
+
+			Ω(source.Next()).Should(Equal(Event{ID: "6", Data: []byte("six")}))
+			Ω(store.Load()).Should(Equal("6"))
+
+			Ω(source.Next()).Should(Equal(Event{ID: "7", Data: []byte("seven")}))
+			Ω(store.Load()).Should(Equal("7"))
+		})
+
+		Context("when acknowledging manually", func() {
+			BeforeEach(func() {
+				config.ManualAck = true
+			})
+
+			It("only saves acknowledged IDs", func() {
+				source, err := config.Connect()
+				Ω(err).ShouldNot(HaveOccurred())
+
+				defer source.Close()
+
+				six, err := source.Next()
+				Ω(err).ShouldNot(HaveOccurred())
+
+				_, err = source.Next()
+				Ω(err).ShouldNot(HaveOccurred())
+
+				Ω(store.Load()).Should(Equal("5"))
+
+				Ω(source.Ack(six)).Should(Succeed())
+				Ω(store.Load()).Should(Equal("6"))
+			})
+		})
+
+		Context("when the ID cannot be loaded", func() {
+			disaster := errors.New("oh no")
+
+			BeforeEach(func() {
+				config.LastEventIDStore = failingIDStore{loadErr: disaster}
+			})
+
+			It("fails to connect", func() {
+				_, err := config.Connect()
+				Ω(err).Should(Equal(disaster))
+				Ω(server.ReceivedRequests()).Should(BeEmpty())
+			})
+		})
+
+		Context("when the ID cannot be saved", func() {
+			disaster := errors.New("oh no")
+
+			BeforeEach(func() {
+				config.LastEventIDStore = failingIDStore{saveErr: disaster}
+				config.RequestCreator = func() *http.Request {
+					request, err := http.NewRequest("GET", server.URL(), nil)
+					Ω(err).ShouldNot(HaveOccurred())
+					request.Header.Set("Last-Event-ID", "5")
+					return request
+				}
+			})
+
+			It("fails and closes the source", func() {
+				source, err := config.Connect()
+				Ω(err).ShouldNot(HaveOccurred())
+
+				_, err = source.Next()
+				Ω(err).Should(MatchError(disaster))
+				Ω(err).Should(MatchError("save last event ID: oh no"))
+
+				_, err = source.Next()
+				Ω(err).Should(Equal(ErrSourceClosed))
+			})
+		})
+	})
+
+	Describe("Events", func() {
+		BeforeEach(func() {
+			server := server
+
+			server.AppendHandlers(
+				func(w http.ResponseWriter, r *http.Request) {
+					w.Header().Add("Content-Type", "text/event-stream; charset=utf-8")
+					w.WriteHeader(http.StatusOK)
+
+					Event{
+						ID:   "1",
+						Data: []byte("hello"),
+					}.Write(w)
+
+					w.(http.Flusher).Flush()
+
+					server.CloseClientConnections()
+				},
+				func(w http.ResponseWriter, r *http.Request) {
+					w.Header().Add("Content-Type", "text/event-stream; charset=utf-8")
+					w.WriteHeader(http.StatusOK)
+
+					Event{
+						ID:   "2",
+						Data: []byte("welcome back"),
+					}.Write(w)
+
+					Event{
+						ID:   "3",
+						Data: []byte("goodbye"),
+					}.Write(w)
+				},
+			)
+		})
+
+		It("iterates over events across reconnects until the stream ends", func() {
+			var ids []string
+			for event, err := range source.Events(context.Background()) {
+				Ω(err).ShouldNot(HaveOccurred())
+				ids = append(ids, event.ID)
+			}
+
+			Ω(ids).Should(Equal([]string{"1", "2", "3"}))
+			Ω(source.ReadyState()).Should(Equal(Closed))
+		})
+
+		It("closes the source when the loop breaks early", func() {
+			for event, err := range source.Events(context.Background()) {
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(event.ID).Should(Equal("1"))
+				break
+			}
+
+			_, err := source.Next()
+			Ω(err).Should(Equal(ErrSourceClosed))
+		})
+
+		It("yields the context's error when it is cancelled", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			var errs []error
+			for _, err := range source.Events(ctx) {
+				errs = append(errs, err)
+			}
+
+			Ω(errs).Should(Equal([]error{context.Canceled}))
+		})
+	})
+
+	Describe("NextContext", func() {
+		var ctx context.Context
+		var cancel context.CancelFunc
+
+		BeforeEach(func() {
+			ctx, cancel = context.WithCancel(context.Background())
+		})
+
+		AfterEach(func() {
+			cancel()
+		})
+
+		Context("when the context is cancelled while reading", func() {
+			BeforeEach(func() {
+				server.AppendHandlers(
+					func(w http.ResponseWriter, r *http.Request) {
+						w.Header().Add("Content-Type", "text/event-stream; charset=utf-8")
+						w.WriteHeader(http.StatusOK)
+
+						Event{
+							ID:   "1",
+							Data: []byte("hello"),
+						}.Write(w)
+
+						w.(http.Flusher).Flush()
+
+						<-r.Context().Done()
+					},
+					ghttp.CombineHandlers(
+						ghttp.VerifyHeaderKV("Last-Event-ID", "1"),
+						func(w http.ResponseWriter, r *http.Request) {
+							w.Header().Add("Content-Type", "text/event-stream; charset=utf-8")
+							w.WriteHeader(http.StatusOK)
+
+							Event{
+								ID:   "2",
+								Data: []byte("welcome back"),
+							}.Write(w)
+						},
+					),
+				)
+			})
+
+			It("returns the context's error without closing the source", func() {
+				Ω(source.NextContext(ctx)).Should(Equal(Event{
+					ID:   "1",
+					Data: []byte("hello"),
+				}))
+
+				time.AfterFunc(100*time.Millisecond, cancel)
+
+				_, err := source.NextContext(ctx)
+				Ω(err).Should(Equal(context.Canceled))
+
+				Ω(source.NextContext(context.Background())).Should(Equal(Event{
+					ID:   "2",
+					Data: []byte("welcome back"),
+				}))
+			})
+		})
+
+		Context("when the context is cancelled while waiting to retry", func() {
+			BeforeEach(func() {
+				server.AppendHandlers(
+					ghttp.RespondWith(http.StatusServiceUnavailable, ""),
+				)
+
+				source = NewEventSource(http.DefaultClient, time.Hour, func() *http.Request {
+					request, err := http.NewRequest("GET", server.URL(), nil)
+					Ω(err).ShouldNot(HaveOccurred())
+					return request
+				})
+			})
+
+			It("returns the context's error", func() {
+				time.AfterFunc(100*time.Millisecond, cancel)
+
+				_, err := source.NextContext(ctx)
+				Ω(err).Should(Equal(context.Canceled))
+			})
+		})
+	})
+
+	Describe("ConnectContext", func() {
+		It("sends requests carrying the context", func() {
+			server.AppendHandlers(
+				func(w http.ResponseWriter, r *http.Request) {
+					w.Header().Add("Content-Type", "text/event-stream; charset=utf-8")
+					w.WriteHeader(http.StatusOK)
+				},
+			)
+
+			doer := &recordingDoer{contexts: make(chan context.Context, 1)}
+
+			ctx := context.WithValue(context.Background(), contextKey{}, "some-value")
+
+			src, err := ConnectContext(ctx, doer, time.Second, func() *http.Request {
+				request, err := http.NewRequest("GET", server.URL(), nil)
+				Ω(err).ShouldNot(HaveOccurred())
+				return request
+			})
+			Ω(err).ShouldNot(HaveOccurred())
+			defer src.Close()
+
+			var requestCtx context.Context
+			Ω(doer.contexts).Should(Receive(&requestCtx))
+			Ω(requestCtx.Value(contextKey{})).Should(Equal("some-value"))
+		})
+
+		Context("when the context is cancelled before the server responds", func() {
+			var unblock chan struct{}
+
+			BeforeEach(func() {
+				unblock = make(chan struct{})
+
+				server.AppendHandlers(
+					func(w http.ResponseWriter, r *http.Request) {
+						select {
+						case <-unblock:
+						case <-r.Context().Done():
+						}
+					},
+				)
+			})
+
+			AfterEach(func() {
+				close(unblock)
+			})
+
+			It("aborts the request and returns the context's error", func() {
+				ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+				defer cancel()
+
+				config := Config{
+					RetryParams: RetryParams{RetryInterval: time.Hour},
+					RequestCreator: func() *http.Request {
+						request, err := http.NewRequest("GET", server.URL(), nil)
+						Ω(err).ShouldNot(HaveOccurred())
+						return request
+					},
+				}
+
+				src, err := config.ConnectContext(ctx)
+				Ω(err).Should(Equal(context.DeadlineExceeded))
+				Ω(src).Should(BeNil())
+			})
+		})
+	})
+
 	for _, retryableStatus := range []int{
 		http.StatusInternalServerError,
 		http.StatusBadGateway,
 		http.StatusServiceUnavailable,
 		http.StatusGatewayTimeout,
+		http.StatusTooManyRequests,
 	} {
 		status := retryableStatus
 
@@ -0,0 +1,24 @@
+package sse
+
+// EventStore records published events so that they can be replayed to clients
+// resuming with a Last-Event-ID.
+//
+// ReplayBuffer keeps recent events in memory, while FileStore persists them
+// to disk so that they survive server restarts.
+type EventStore interface {
+	// Append records the event.
+	Append(Event) error
+
+	// Since returns the recorded events which followed the event with the
+	// given ID, oldest first. If lastEventID is empty, every retained event is
+	// returned. If the event is not retained, ErrEventNotFound is returned.
+	Since(lastEventID string) ([]Event, error)
+}
+
+// deferredStore is implemented by stores which can find where to replay from
+// separately from reading the events, e.g. FileStore, which reads them from
+// disk. Broker only holds its lock while finding them, so that publishing is
+// not held up by a resuming client.
+type deferredStore interface {
+	since(lastEventID string) (func() ([]Event, error), error)
+}
@@ -1,6 +1,11 @@
 package sse_test
 
 import (
+	"encoding/json"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"strings"
 	"time"
 
 	. "github.com/vito/go-sse/sse"
@@ -36,6 +41,106 @@ var _ = Describe("Event", func() {
 				Retry: 123 * time.Millisecond,
 			}.Encode()).Should(Equal("id: some-id\nevent: some-name\nretry: 123\ndata: some-data\n\n"))
 		})
+
+		It("omits an empty id and name", func() {
+			Ω(Event{
+				Data: []byte("some-data"),
+			}.Encode()).Should(Equal("data: some-data\n\n"))
+		})
+
+		It("includes an empty id to reset the last event ID", func() {
+			Ω(Event{
+				ResetID: true,
+				Data:    []byte("some-data"),
+			}.Encode()).Should(Equal("id\ndata: some-data\n\n"))
+		})
+
+		It("splits data on CR, LF, and CRLF", func() {
+			Ω(Event{
+				Data: []byte("a\rb\nc\r\nd\r\r\n"),
+			}.Encode()).Should(Equal("data: a\ndata: b\ndata: c\ndata: d\ndata\ndata\n\n"))
+		})
+
+		It("sanitizes fields which would otherwise forge others", func() {
+			Ω(Event{
+				ID:    "1\nevent: forged\x00",
+				Name:  "name\r\ndata: forged\x00",
+				Retry: -time.Second,
+				Data:  []byte("some-data"),
+				Fields: []Field{
+					{Name: "data", Value: "forged"},
+					{Name: "vendor:forged", Value: "forged"},
+					{Name: "vendor", Value: "extension\nforged"},
+				},
+			}.Encode()).Should(Equal("id: 1event: forged\nevent: namedata: forged\nvendor: extensionforged\ndata: some-data\n\n"))
+		})
+
+		It("round-trips through the reader", func() {
+			events := []Event{
+				{ID: "1", Name: "some-name", Data: []byte("some-data")},
+				{ID: "1", Data: []byte("inherits the id")},
+				{ResetID: true, Data: []byte("resets the id")},
+				{Data: []byte("has no id\n")},
+			}
+
+			var stream strings.Builder
+			for _, event := range events {
+				stream.WriteString(event.Encode())
+			}
+
+			readCloser := NewReadCloser(io.NopCloser(strings.NewReader(stream.String())))
+			for _, event := range events {
+				Ω(readCloser.Next()).Should(Equal(event))
+			}
+		})
+
+		It("includes extra fields before the data", func() {
+			Ω(Event{
+				ID:   "some-id",
+				Name: "some-name",
+				Data: []byte("some-data"),
+				Fields: []Field{
+					{Name: "vendor", Value: "extension"},
+					{Name: "custom", Value: ""},
+				},
+			}.Encode()).Should(Equal("id: some-id\nevent: some-name\nvendor: extension\ncustom: \ndata: some-data\n\n"))
+		})
+	})
+
+	Describe("Validate", func() {
+		It("accepts valid events", func() {
+			Ω(Event{
+				ID:     "some-id",
+				Name:   "some-name",
+				Data:   []byte("any\r\ndata\x00"),
+				Fields: []Field{{Name: "vendor", Value: "extension"}},
+			}.Validate()).Should(Succeed())
+		})
+
+		DescribeTable("rejects events which cannot be encoded safely",
+			func(event Event, field string) {
+				err := event.Validate()
+
+				var validationErr *ValidationError
+				Ω(errors.As(err, &validationErr)).Should(BeTrue())
+				Ω(validationErr.Field).Should(Equal(field))
+			},
+			Entry("id with LF", Event{ID: "1\n"}, "id"),
+			Entry("id with CR", Event{ID: "1\r"}, "id"),
+			Entry("id with NULL", Event{ID: "1\x00"}, "id"),
+			Entry("name with LF", Event{Name: "a\nb"}, "event"),
+			Entry("name with CR", Event{Name: "a\rb"}, "event"),
+			Entry("name with NULL", Event{Name: "a\x00b"}, "event"),
+			Entry("negative retry", Event{Retry: -time.Second}, "retry"),
+			Entry("extra field named after a standard one", Event{Fields: []Field{{Name: "data"}}}, "data"),
+			Entry("extra field with a colon in its name", Event{Fields: []Field{{Name: "a:b"}}}, "a:b"),
+			Entry("extra field with an empty name", Event{Fields: []Field{{Name: ""}}}, ""),
+			Entry("extra field with LF in its value", Event{Fields: []Field{{Name: "vendor", Value: "a\nb"}}}, "vendor"),
+		)
+
+		It("describes the problem", func() {
+			Ω(Event{ID: "1\n"}.Validate()).Should(MatchError("invalid event id: contains CR, LF, or NULL"))
+		})
 	})
 
 	Describe("Write", func() {
@@ -50,6 +155,9 @@ var _ = Describe("Event", func() {
 				ID:   "some-id",
 				Name: "some-name",
 				Data: []byte("some-data\nsome-more-data\n"),
+				Fields: []Field{
+					{Name: "vendor", Value: "extension"},
+				},
 			}
 
 			err := event.Write(destination)
@@ -57,5 +165,171 @@ var _ = Describe("Event", func() {
 
 			Ω(destination.Contents()).Should(Equal([]byte(event.Encode())))
 		})
+
+		It("writes nothing for an invalid event", func() {
+			err := Event{ID: "1\nevent: forged"}.Write(destination)
+
+			var validationErr *ValidationError
+			Ω(errors.As(err, &validationErr)).Should(BeTrue())
+			Ω(destination.Contents()).Should(BeEmpty())
+		})
+	})
+
+	Describe("text encoding", func() {
+		It("marshals to the wire format", func() {
+			event := Event{ID: "1", Name: "some-name", Data: []byte("some-data")}
+
+			text, err := event.MarshalText()
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(string(text)).Should(Equal(event.Encode()))
+		})
+
+		It("refuses to marshal an invalid event", func() {
+			_, err := Event{ID: "1\n"}.MarshalText()
+
+			var validationErr *ValidationError
+			Ω(errors.As(err, &validationErr)).Should(BeTrue())
+		})
+
+		It("unmarshals the first event, keeping unknown fields", func() {
+			var event Event
+			Ω(event.UnmarshalText([]byte("id: 1\nvendor: extension\ndata: a\n\ndata: b\n\n"))).Should(Succeed())
+			Ω(event).Should(Equal(Event{
+				ID:     "1",
+				Data:   []byte("a"),
+				Fields: []Field{{Name: "vendor", Value: "extension"}},
+			}))
+		})
+
+		It("fails to unmarshal text without a complete event", func() {
+			var event Event
+			Ω(event.UnmarshalText([]byte("data: a\n"))).Should(MatchError(io.ErrUnexpectedEOF))
+		})
+	})
+
+	Describe("JSON encoding", func() {
+		It("has a stable schema", func() {
+			payload, err := json.Marshal(Event{
+				ID:     "1",
+				Name:   "some-name",
+				Data:   []byte("some-data"),
+				Retry:  1500 * time.Millisecond,
+				Fields: []Field{{Name: "vendor", Value: "extension"}},
+			})
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(payload).Should(MatchJSON(`{
+				"id": "1",
+				"event": "some-name",
+				"data": "some-data",
+				"retry": 1500,
+				"fields": [{"name": "vendor", "value": "extension"}]
+			}`))
+		})
+
+		It("encodes data which is not valid UTF-8 as base64", func() {
+			payload, err := json.Marshal(Event{Data: []byte{0xff, 0x00}})
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(payload).Should(MatchJSON(`{"data_base64": "/wA="}`))
+
+			var event Event
+			Ω(json.Unmarshal(payload, &event)).Should(Succeed())
+			Ω(event).Should(Equal(Event{Data: []byte{0xff, 0x00}}))
+		})
+
+		It("encodes an explicit ID reset", func() {
+			payload, err := json.Marshal(Event{ResetID: true, Data: []byte{}})
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(payload).Should(MatchJSON(`{"reset_id": true, "data": ""}`))
+		})
+
+		It("rejects both forms of data at once", func() {
+			var event Event
+			Ω(json.Unmarshal([]byte(`{"data": "a", "data_base64": "Yg=="}`), &event)).ShouldNot(Succeed())
+		})
+	})
+
+	Describe("round trips", func() {
+		var random *rand.Rand
+
+		BeforeEach(func() {
+			random = rand.New(rand.NewPCG(uint64(GinkgoRandomSeed()), 0))
+		})
+
+		// randomString returns a short string of the given characters, which
+		// include spaces and multi-byte characters to exercise the edge cases
+		randomString := func(alphabet string) string {
+			chars := []rune(alphabet)
+
+			var builder strings.Builder
+			for range random.IntN(8) {
+				builder.WriteRune(chars[random.IntN(len(chars))])
+			}
+
+			return builder.String()
+		}
+
+		// randomEvent returns an arbitrary event which can be encoded exactly,
+		// i.e. which is valid, has millisecond precision, and only uses LF in
+		// its data
+		randomEvent := func() Event {
+			event := Event{
+				ID:    randomString(" a1:é😀"),
+				Name:  randomString(" b2:é😀"),
+				Data:  []byte(randomString(" c3:\né😀")),
+				Retry: time.Duration(random.IntN(3)) * time.Duration(random.IntN(100000)) * time.Millisecond,
+			}
+
+			if random.IntN(8) == 0 {
+				event.Data = nil
+			}
+
+			event.ResetID = event.ID == "" && random.IntN(2) == 0
+
+			for range random.IntN(3) {
+				event.Fields = append(event.Fields, Field{
+					Name:  "x-" + randomString("abc"),
+					Value: randomString(" d4:é😀"),
+				})
+			}
+
+			return event
+		}
+
+		It("decodes what it encodes as text", func() {
+			for range 1000 {
+				event := randomEvent()
+				if random.IntN(4) == 0 {
+					event.Data = append(event.Data, 0xfe, 0xff)
+				}
+
+				text, err := event.MarshalText()
+				Ω(err).ShouldNot(HaveOccurred())
+
+				// nil data is written as empty data
+				if event.Data == nil {
+					event.Data = []byte{}
+				}
+
+				var decoded Event
+				Ω(decoded.UnmarshalText(text)).Should(Succeed())
+				Ω(decoded).Should(Equal(event), "encoded as %q", text)
+			}
+		})
+
+		It("decodes what it encodes as JSON", func() {
+			for range 1000 {
+				event := randomEvent()
+				if random.IntN(4) == 0 {
+					event.Data = append(event.Data, 0xff)
+				}
+
+				payload, err := json.Marshal(event)
+				Ω(err).ShouldNot(HaveOccurred())
+
+				var decoded Event
+				Ω(json.Unmarshal(payload, &decoded)).Should(Succeed())
+				Ω(decoded).Should(Equal(event), "encoded as %s", payload)
+			}
+		})
 	})
 })
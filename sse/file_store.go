@@ -0,0 +1,478 @@
+package sse
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultSegmentSize is the size at which a FileStore starts a new segment
+// when SegmentSize is not set.
+const DefaultSegmentSize = 4 << 20
+
+const segmentSuffix = ".log"
+
+// events are recorded in their wire format, so every record ends with the
+// blank line that dispatches it, and no blank line appears anywhere else
+var recordTerminator = []byte("\n\n")
+
+// FileStoreOptions configures the segmenting and retention of a FileStore.
+type FileStoreOptions struct {
+	// SegmentSize is the size in bytes at which the current segment is closed
+	// and a new one is started. Defaults to DefaultSegmentSize.
+	SegmentSize int64
+
+	// MaxBytes is the total size in bytes of the segments to retain. When
+	// exceeded, the oldest segments are removed. Zero means no limit.
+	MaxBytes int64
+
+	// MaxAge is how long to retain a closed segment after its last event was
+	// appended. Zero means no limit.
+	MaxAge time.Duration
+}
+
+// FileStore is an EventStore which persists events to an append-only log on
+// disk, so that clients can resume across server restarts.
+//
+// The log is split into segment files named by sequence number within a
+// directory. Each event is appended to the newest segment in the same wire
+// format written by Event.Write. Retention is enforced a segment at a time:
+// whenever a segment fills up, the oldest segments beyond MaxBytes or MaxAge
+// are removed. The newest segment is never removed.
+//
+// If the process crashes mid-append, the partially written event is
+// discarded the next time the store is opened.
+type FileStore struct {
+	dir     string
+	options FileStoreOptions
+
+	lock sync.Mutex
+
+	// oldest first; the last segment is the one being appended to
+	segments []*fileSegment
+	active   *os.File
+
+	// position of the most recent event with each ID
+	index map[string]recordPosition
+}
+
+type fileSegment struct {
+	seq      uint64
+	size     int64
+	modified time.Time
+}
+
+type recordPosition struct {
+	segment uint64
+	offset  int64
+}
+
+// OpenFileStore opens the store in the given directory, creating it if
+// necessary, and recovers any events already recorded there.
+func OpenFileStore(dir string, options FileStoreOptions) (*FileStore, error) {
+	if options.SegmentSize <= 0 {
+		options.SegmentSize = DefaultSegmentSize
+	}
+
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var seqs []uint64
+	for _, entry := range entries {
+		numeral, isSegment := strings.CutSuffix(entry.Name(), segmentSuffix)
+		if !isSegment || entry.IsDir() {
+			continue
+		}
+
+		seq, err := strconv.ParseUint(numeral, 10, 64)
+		if err != nil {
+			continue
+		}
+
+		seqs = append(seqs, seq)
+	}
+
+	slices.Sort(seqs)
+
+	store := &FileStore{
+		dir:     dir,
+		options: options,
+		index:   map[string]recordPosition{},
+	}
+
+	for i, seq := range seqs {
+		segment, err := store.recoverSegment(seq, i == len(seqs)-1)
+		if err != nil {
+			return nil, err
+		}
+
+		store.segments = append(store.segments, segment)
+	}
+
+	if len(store.segments) == 0 {
+		err = store.createSegment(1)
+	} else {
+		store.active, err = store.openSegment(store.segments[len(store.segments)-1].seq)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return store, nil
+}
+
+// Append writes the event to the end of the log, starting a new segment and
+// enforcing retention if the current segment is full.
+func (store *FileStore) Append(event Event) error {
+	var record bytes.Buffer
+	err := event.Write(&record)
+	if err != nil {
+		return err
+	}
+
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	if store.active == nil {
+		return os.ErrClosed
+	}
+
+	active := store.segments[len(store.segments)-1]
+	if active.size > 0 && active.size+int64(record.Len()) > store.options.SegmentSize {
+		err := store.roll()
+		if err != nil {
+			return err
+		}
+
+		active = store.segments[len(store.segments)-1]
+	}
+
+	_, err = store.active.Write(record.Bytes())
+	if err != nil {
+		// don't leave a partial record behind for the next append to follow
+		store.active.Truncate(active.size)
+		return err
+	}
+
+	if event.ID != "" {
+		store.index[event.ID] = recordPosition{
+			segment: active.seq,
+			offset:  active.size,
+		}
+	}
+
+	active.size += int64(record.Len())
+	active.modified = time.Now()
+
+	return nil
+}
+
+// Since returns the events appended after the most recent event with the
+// given ID, oldest first. If lastEventID is empty, every retained event is
+// returned. If the event is not retained, ErrEventNotFound is returned.
+//
+// Only finding the event holds up Append; the segments are read afterwards.
+func (store *FileStore) Since(lastEventID string) ([]Event, error) {
+	read, err := store.since(lastEventID)
+	if err != nil {
+		return nil, err
+	}
+
+	return read()
+}
+
+// since finds the event with the given ID and notes how far the log extends,
+// returning a function which reads the events in between. Events appended in
+// the meantime are not returned.
+func (store *FileStore) since(lastEventID string) (func() ([]Event, error), error) {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	start := recordPosition{segment: store.segments[0].seq}
+
+	if lastEventID != "" {
+		position, found := store.index[lastEventID]
+		if !found {
+			return nil, ErrEventNotFound
+		}
+
+		start = position
+	}
+
+	// copy the segments, as the active one grows as events are appended
+	var segments []fileSegment
+	for _, segment := range store.segments {
+		if segment.seq >= start.segment {
+			segments = append(segments, *segment)
+		}
+	}
+
+	return func() ([]Event, error) {
+		events := []Event{}
+		for _, segment := range segments {
+			data, err := store.readSegment(&segment)
+			if errors.Is(err, fs.ErrNotExist) {
+				// compacted since; it is no longer retained either way
+				continue
+			}
+
+			if err != nil {
+				return nil, err
+			}
+
+			if segment.seq == start.segment {
+				data = data[start.offset:]
+
+				if lastEventID != "" {
+					// skip the last event the client saw
+					_, data = nextRecord(data)
+				}
+			}
+
+			for {
+				var record []byte
+				record, data = nextRecord(data)
+				if record == nil {
+					break
+				}
+
+				event, err := decodeRecord(record)
+				if err != nil {
+					return nil, err
+				}
+
+				events = append(events, event)
+			}
+		}
+
+		return events, nil
+	}, nil
+}
+
+// Lookup returns the most recent retained event with the given ID, or
+// ErrEventNotFound.
+func (store *FileStore) Lookup(id string) (Event, error) {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	position, found := store.index[id]
+	if !found {
+		return Event{}, ErrEventNotFound
+	}
+
+	for _, segment := range store.segments {
+		if segment.seq != position.segment {
+			continue
+		}
+
+		data, err := store.readSegment(segment)
+		if err != nil {
+			return Event{}, err
+		}
+
+		record, _ := nextRecord(data[position.offset:])
+
+		return decodeRecord(record)
+	}
+
+	return Event{}, ErrEventNotFound
+}
+
+// Compact removes the oldest segments which fall outside of MaxBytes or
+// MaxAge. This is done automatically whenever a new segment is started, but
+// may also be called periodically to enforce MaxAge during quiet periods.
+func (store *FileStore) Compact() error {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	return store.compact()
+}
+
+// Close closes the current segment. Subsequent appends will fail.
+func (store *FileStore) Close() error {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	if store.active == nil {
+		return os.ErrClosed
+	}
+
+	err := store.active.Close()
+	store.active = nil
+
+	return err
+}
+
+func (store *FileStore) roll() error {
+	err := store.active.Close()
+	if err != nil {
+		return err
+	}
+
+	err = store.createSegment(store.segments[len(store.segments)-1].seq + 1)
+	if err != nil {
+		return err
+	}
+
+	return store.compact()
+}
+
+func (store *FileStore) compact() error {
+	var total int64
+	for _, segment := range store.segments {
+		total += segment.size
+	}
+
+	now := time.Now()
+
+	for len(store.segments) > 1 {
+		oldest := store.segments[0]
+
+		expired := store.options.MaxAge != 0 && now.Sub(oldest.modified) > store.options.MaxAge
+		oversized := store.options.MaxBytes != 0 && total > store.options.MaxBytes
+		if !expired && !oversized {
+			break
+		}
+
+		err := os.Remove(store.segmentPath(oldest.seq))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+
+		for id, position := range store.index {
+			if position.segment == oldest.seq {
+				delete(store.index, id)
+			}
+		}
+
+		total -= oldest.size
+		store.segments = store.segments[1:]
+	}
+
+	return nil
+}
+
+func (store *FileStore) createSegment(seq uint64) error {
+	file, err := store.openSegment(seq)
+	if err != nil {
+		return err
+	}
+
+	store.active = file
+	store.segments = append(store.segments, &fileSegment{
+		seq:      seq,
+		modified: time.Now(),
+	})
+
+	return nil
+}
+
+func (store *FileStore) openSegment(seq uint64) (*os.File, error) {
+	return os.OpenFile(store.segmentPath(seq), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+}
+
+// recoverSegment indexes the events in a segment. If the segment is the one
+// that was being appended to, anything following its last complete event is
+// the remains of an interrupted append and is truncated.
+func (store *FileStore) recoverSegment(seq uint64, active bool) (*fileSegment, error) {
+	path := store.segmentPath(seq)
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var offset int64
+	for remaining := data; ; {
+		var record []byte
+		record, remaining = nextRecord(remaining)
+		if record == nil {
+			break
+		}
+
+		event, err := decodeRecord(record)
+		if err == nil && event.ID != "" {
+			store.index[event.ID] = recordPosition{segment: seq, offset: offset}
+		}
+
+		offset += int64(len(record))
+	}
+
+	if active && offset < int64(len(data)) {
+		err := os.Truncate(path, offset)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &fileSegment{
+		seq:      seq,
+		size:     offset,
+		modified: info.ModTime(),
+	}, nil
+}
+
+func (store *FileStore) readSegment(segment *fileSegment) ([]byte, error) {
+	file, err := os.Open(store.segmentPath(segment.seq))
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	data := make([]byte, segment.size)
+	_, err = io.ReadFull(file, data)
+	if err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+func (store *FileStore) segmentPath(seq uint64) string {
+	return filepath.Join(store.dir, fmt.Sprintf("%020d%s", seq, segmentSuffix))
+}
+
+// nextRecord splits the first complete record off of data, returning nil if
+// there is none.
+func nextRecord(data []byte) ([]byte, []byte) {
+	end := bytes.Index(data, recordTerminator)
+	if end == -1 {
+		return nil, data
+	}
+
+	end += len(recordTerminator)
+
+	return data[:end], data[end:]
+}
+
+// decodeRecord parses a record, leaving the event exactly as it was
+// appended, including its data and extra fields.
+func decodeRecord(record []byte) (Event, error) {
+	return NewReadCloserWithOptions(io.NopCloser(bytes.NewReader(record)), ReaderOptions{
+		InvalidUTF8:       PassInvalidUTF8,
+		KeepUnknownFields: true,
+	}).Next()
+}
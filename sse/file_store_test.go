@@ -0,0 +1,202 @@
+package sse_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/vito/go-sse/sse"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("FileStore", func() {
+	var (
+		dir     string
+		options FileStoreOptions
+		store   *FileStore
+	)
+
+	event := func(id int) Event {
+		return Event{ID: fmt.Sprintf("%d", id), Name: "some-name", Data: []byte("some\ndata")}
+	}
+
+	segments := func() []string {
+		matches, err := filepath.Glob(filepath.Join(dir, "*.log"))
+		Ω(err).ShouldNot(HaveOccurred())
+		return matches
+	}
+
+	BeforeEach(func() {
+		dir = GinkgoT().TempDir()
+		options = FileStoreOptions{}
+	})
+
+	JustBeforeEach(func() {
+		var err error
+		store, err = OpenFileStore(dir, options)
+		Ω(err).ShouldNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		store.Close()
+	})
+
+	reopen := func() {
+		Ω(store.Close()).Should(Succeed())
+
+		var err error
+		store, err = OpenFileStore(dir, options)
+		Ω(err).ShouldNot(HaveOccurred())
+	}
+
+	Context("with events appended", func() {
+		JustBeforeEach(func() {
+			for i := 1; i <= 3; i++ {
+				Ω(store.Append(event(i))).Should(Succeed())
+			}
+		})
+
+		It("returns the events after the given ID", func() {
+			Ω(store.Since("1")).Should(Equal([]Event{event(2), event(3)}))
+		})
+
+		It("returns every event for an empty ID", func() {
+			Ω(store.Since("")).Should(Equal([]Event{event(1), event(2), event(3)}))
+		})
+
+		It("returns ErrEventNotFound for an unknown ID", func() {
+			_, err := store.Since("bogus")
+			Ω(err).Should(Equal(ErrEventNotFound))
+		})
+
+		It("looks up events by ID", func() {
+			Ω(store.Lookup("2")).Should(Equal(event(2)))
+
+			_, err := store.Lookup("bogus")
+			Ω(err).Should(Equal(ErrEventNotFound))
+		})
+
+		It("retains events across restarts", func() {
+			reopen()
+
+			Ω(store.Since("1")).Should(Equal([]Event{event(2), event(3)}))
+
+			Ω(store.Append(event(4))).Should(Succeed())
+			Ω(store.Since("3")).Should(Equal([]Event{event(4)}))
+		})
+
+		Context("when the last append was interrupted", func() {
+			JustBeforeEach(func() {
+				Ω(store.Close()).Should(Succeed())
+
+				file, err := os.OpenFile(segments()[0], os.O_WRONLY|os.O_APPEND, 0644)
+				Ω(err).ShouldNot(HaveOccurred())
+
+				_, err = file.Write([]byte("id: 4\nevent: some-name\ndata: so"))
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(file.Close()).Should(Succeed())
+
+				store, err = OpenFileStore(dir, options)
+				Ω(err).ShouldNot(HaveOccurred())
+			})
+
+			It("discards the torn event", func() {
+				Ω(store.Since("")).Should(Equal([]Event{event(1), event(2), event(3)}))
+
+				Ω(store.Append(event(5))).Should(Succeed())
+				Ω(store.Since("3")).Should(Equal([]Event{event(5)}))
+			})
+		})
+	})
+
+	Context("with data which is not valid UTF-8", func() {
+		binary := Event{ID: "1", Data: []byte{0xff, 0xfe}}
+
+		JustBeforeEach(func() {
+			Ω(store.Append(binary)).Should(Succeed())
+		})
+
+		It("replays it as it was appended", func() {
+			Ω(store.Since("")).Should(Equal([]Event{binary}))
+			Ω(store.Lookup("1")).Should(Equal(binary))
+		})
+	})
+
+	Context("with extra fields", func() {
+		extended := Event{
+			ID:     "1",
+			Data:   []byte("hello"),
+			Fields: []Field{{Name: "vendor", Value: "extension"}},
+		}
+
+		JustBeforeEach(func() {
+			Ω(store.Append(extended)).Should(Succeed())
+		})
+
+		It("replays them", func() {
+			Ω(store.Since("")).Should(Equal([]Event{extended}))
+			Ω(store.Lookup("1")).Should(Equal(extended))
+
+			reopen()
+
+			Ω(store.Since("")).Should(Equal([]Event{extended}))
+		})
+	})
+
+	Context("when segments fill up", func() {
+		BeforeEach(func() {
+			// roughly two events per segment
+			options.SegmentSize = 100
+		})
+
+		It("starts new segments", func() {
+			for i := 1; i <= 6; i++ {
+				Ω(store.Append(event(i))).Should(Succeed())
+			}
+
+			Ω(len(segments())).Should(BeNumerically(">", 1))
+			Ω(store.Since("1")).Should(HaveLen(5))
+		})
+
+		Context("and exceed the maximum size", func() {
+			BeforeEach(func() {
+				options.MaxBytes = 160
+			})
+
+			It("removes the oldest segments", func() {
+				for i := 1; i <= 10; i++ {
+					Ω(store.Append(event(i))).Should(Succeed())
+				}
+
+				events, err := store.Since("")
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(len(events)).Should(BeNumerically("<", 10))
+				Ω(events[len(events)-1]).Should(Equal(event(10)))
+
+				_, err = store.Since("1")
+				Ω(err).Should(Equal(ErrEventNotFound))
+			})
+		})
+
+		Context("and exceed the maximum age", func() {
+			BeforeEach(func() {
+				options.MaxAge = 100 * time.Millisecond
+			})
+
+			It("removes expired segments when compacted", func() {
+				for i := 1; i <= 3; i++ {
+					Ω(store.Append(event(i))).Should(Succeed())
+				}
+
+				time.Sleep(150 * time.Millisecond)
+
+				Ω(store.Compact()).Should(Succeed())
+
+				Ω(store.Since("")).Should(Equal([]Event{event(3)}))
+			})
+		})
+	})
+})
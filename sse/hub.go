@@ -0,0 +1,96 @@
+package sse
+
+import (
+	"net/http"
+	"strings"
+)
+
+// TopicFunc determines the topics a request subscribes to.
+type TopicFunc func(*http.Request) []string
+
+// TopicsFromQuery subscribes a request to the topics named by the given query
+// parameter. The parameter may be repeated, and each value may list several
+// comma-separated topics, e.g. ?topic=builds,logs&topic=alerts.
+func TopicsFromQuery(param string) TopicFunc {
+	return func(r *http.Request) []string {
+		var topics []string
+		for _, value := range r.URL.Query()[param] {
+			for _, topic := range strings.Split(value, ",") {
+				if topic != "" {
+					topics = append(topics, topic)
+				}
+			}
+		}
+
+		return topics
+	}
+}
+
+// TopicsFromPathValue subscribes a request to the topic matched by the named
+// wildcard of its http.ServeMux pattern, e.g. "topic" in
+// "/events/{topic...}".
+func TopicsFromPathValue(name string) TopicFunc {
+	return func(r *http.Request) []string {
+		topic := r.PathValue(name)
+		if topic == "" {
+			return nil
+		}
+
+		return []string{topic}
+	}
+}
+
+// TopicMatches reports whether a subscription pattern matches a published
+// topic. A pattern ending in "*" matches every topic beginning with the rest
+// of the pattern, so "builds.*" matches "builds.123" and "*" matches
+// everything; any other pattern must equal the topic exactly.
+func TopicMatches(pattern string, topic string) bool {
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+		return strings.HasPrefix(topic, prefix)
+	}
+
+	return pattern == topic
+}
+
+// Hub is an http.Handler which streams events to clients subscribed to named
+// topics. It behaves like a Broker, except that each request subscribes to
+// the topics returned by Topics and only receives events published to a
+// matching topic.
+//
+// Requests which do not subscribe to any topic are rejected with 400 Bad
+// Request.
+type Hub struct {
+	// Topics determines the topics each request subscribes to. Required.
+	Topics TopicFunc
+
+	// BufferSize is the number of events queued for each client before it is
+	// considered too slow and disconnected. Defaults to
+	// DefaultBrokerBufferSize.
+	BufferSize int
+
+	broker Broker
+}
+
+// ServeHTTP subscribes the request to its topics and streams matching events
+// to it until the request's context is cancelled.
+func (hub *Hub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	topics := hub.Topics(r)
+	if len(topics) == 0 {
+		http.Error(w, "no topics to subscribe to", http.StatusBadRequest)
+		return
+	}
+
+	hub.broker.serve(w, r, hub.BufferSize, topics)
+}
+
+// Publish queues the event for every client subscribed to a pattern matching
+// the topic. An invalid event is not published, and a *ValidationError is
+// returned.
+func (hub *Hub) Publish(topic string, event Event) error {
+	return hub.broker.publish(topic, event)
+}
+
+// Clients returns the number of currently connected clients.
+func (hub *Hub) Clients() int {
+	return hub.broker.Clients()
+}
@@ -0,0 +1,122 @@
+package sse_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/vito/go-sse/sse"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Hub", func() {
+	var (
+		hub    *Hub
+		server *httptest.Server
+	)
+
+	BeforeEach(func() {
+		hub = &Hub{Topics: TopicsFromQuery("topic")}
+		server = httptest.NewServer(hub)
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	subscribe := func(path string) *EventSource {
+		source, err := Connect(http.DefaultClient, 100*time.Millisecond, func() *http.Request {
+			request, err := http.NewRequest("GET", server.URL+path, nil)
+			Ω(err).ShouldNot(HaveOccurred())
+			return request
+		})
+		Ω(err).ShouldNot(HaveOccurred())
+
+		return source
+	}
+
+	It("only delivers events published to subscribed topics", func() {
+		builds := subscribe("/?topic=builds")
+		defer builds.Close()
+
+		everything := subscribe("/?topic=builds,logs&topic=alerts")
+		defer everything.Close()
+
+		hub.Publish("logs", Event{ID: "1", Data: []byte("log line")})
+		hub.Publish("builds", Event{ID: "2", Data: []byte("build started")})
+		hub.Publish("alerts", Event{ID: "3", Data: []byte("on fire")})
+
+		Ω(builds.Next()).Should(Equal(Event{ID: "2", Data: []byte("build started")}))
+
+		Ω(everything.Next()).Should(Equal(Event{ID: "1", Data: []byte("log line")}))
+		Ω(everything.Next()).Should(Equal(Event{ID: "2", Data: []byte("build started")}))
+		Ω(everything.Next()).Should(Equal(Event{ID: "3", Data: []byte("on fire")}))
+	})
+
+	It("supports prefix subscriptions", func() {
+		builds := subscribe("/?topic=builds.*")
+		defer builds.Close()
+
+		hub.Publish("logs.1", Event{ID: "1", Data: []byte("log line")})
+		hub.Publish("builds.1", Event{ID: "2", Data: []byte("build started")})
+
+		Ω(builds.Next()).Should(Equal(Event{ID: "2", Data: []byte("build started")}))
+	})
+
+	It("publishes JSON", func() {
+		logs := subscribe("/?topic=logs")
+		defer logs.Close()
+
+		Ω(hub.PublishJSON("logs", Event{ID: "1"}, map[string]string{"line": "hello"})).Should(Succeed())
+
+		Ω(logs.Next()).Should(Equal(Event{ID: "1", Data: []byte(`{"line":"hello"}`)}))
+	})
+
+	It("rejects invalid events", func() {
+		var validationErr *ValidationError
+		Ω(errors.As(hub.Publish("logs", Event{Name: "log\r\nevent: forged"}), &validationErr)).Should(BeTrue())
+	})
+
+	It("rejects requests without topics", func() {
+		response, err := http.Get(server.URL)
+		Ω(err).ShouldNot(HaveOccurred())
+		defer response.Body.Close()
+
+		Ω(response.StatusCode).Should(Equal(http.StatusBadRequest))
+		Ω(hub.Clients()).Should(BeZero())
+	})
+
+	Describe("TopicsFromPathValue", func() {
+		It("subscribes to the topic matched by the route", func() {
+			mux := http.NewServeMux()
+			mux.Handle("/events/{topic...}", hub)
+			server.Config.Handler = mux
+
+			hub.Topics = TopicsFromPathValue("topic")
+
+			builds := subscribe("/events/builds/123")
+			defer builds.Close()
+
+			hub.Publish("builds/456", Event{ID: "1", Data: []byte("other build")})
+			hub.Publish("builds/123", Event{ID: "2", Data: []byte("this build")})
+
+			Ω(builds.Next()).Should(Equal(Event{ID: "2", Data: []byte("this build")}))
+		})
+	})
+
+	Describe("TopicMatches", func() {
+		It("matches exact topics", func() {
+			Ω(TopicMatches("builds", "builds")).Should(BeTrue())
+			Ω(TopicMatches("builds", "builds.1")).Should(BeFalse())
+		})
+
+		It("matches prefixes ending in a wildcard", func() {
+			Ω(TopicMatches("builds.*", "builds.1")).Should(BeTrue())
+			Ω(TopicMatches("builds.*", "logs.1")).Should(BeFalse())
+			Ω(TopicMatches("*", "anything")).Should(BeTrue())
+		})
+	})
+})
@@ -0,0 +1,56 @@
+package sse
+
+import (
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+// idleReader calls abort and closes the body if a read from it waits longer
+// than the timeout for anything to arrive, after which reads fail with
+// ErrIdleTimeout. Closing the body unblocks the read even if it does not
+// observe the context aborted by abort.
+// The time between reads is not counted, so that a slow consumer is not
+// mistaken for a quiet server.
+type idleReader struct {
+	body    io.ReadCloser
+	timeout time.Duration
+	timer   *time.Timer
+
+	timedOut atomic.Bool
+}
+
+func newIdleReader(body io.ReadCloser, timeout time.Duration, abort func()) *idleReader {
+	reader := &idleReader{
+		body:    body,
+		timeout: timeout,
+	}
+
+	reader.timer = time.AfterFunc(timeout, func() {
+		reader.timedOut.Store(true)
+		abort()
+		body.Close()
+	})
+
+	// only runs while reading
+	reader.timer.Stop()
+
+	return reader
+}
+
+func (reader *idleReader) Read(p []byte) (int, error) {
+	reader.timer.Reset(reader.timeout)
+	n, err := reader.body.Read(p)
+	reader.timer.Stop()
+
+	if reader.timedOut.Load() {
+		return n, ErrIdleTimeout
+	}
+
+	return n, err
+}
+
+func (reader *idleReader) Close() error {
+	reader.timer.Stop()
+	return reader.body.Close()
+}
@@ -0,0 +1,203 @@
+package sse
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+)
+
+// ErrUnregisteredEvent is returned by JSONTypes.Decode for an event whose name
+// has no registered type.
+var ErrUnregisteredEvent = errors.New("no type registered for event")
+
+// DecodeError is returned when an event's data cannot be decoded. It only
+// concerns that event, so reading may continue with the next one.
+type DecodeError struct {
+	Event Event
+	Err   error
+}
+
+func (err *DecodeError) Error() string {
+	return fmt.Sprintf("decode event %q: %s", err.Event.ID, err.Err)
+}
+
+func (err *DecodeError) Unwrap() error {
+	return err.Err
+}
+
+// DecodeJSON decodes the event's data as JSON into a T. Failures are
+// returned as a *DecodeError.
+func DecodeJSON[T any](event Event) (T, error) {
+	var value T
+	err := json.Unmarshal(event.Data, &value)
+	if err != nil {
+		return value, &DecodeError{Event: event, Err: err}
+	}
+
+	return value, nil
+}
+
+// NewJSONEvent returns a copy of the event with its data set to v encoded as
+// JSON.
+func NewJSONEvent(event Event, v any) (Event, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return Event{}, err
+	}
+
+	event.Data = data
+
+	return event, nil
+}
+
+// EncodeJSON writes the event with its data set to v encoded as JSON.
+func (encoder *Encoder) EncodeJSON(event Event, v any) error {
+	event, err := NewJSONEvent(event, v)
+	if err != nil {
+		return err
+	}
+
+	return encoder.Encode(event)
+}
+
+// PublishJSON publishes the event with its data set to v encoded as JSON.
+func (broker *Broker) PublishJSON(event Event, v any) error {
+	event, err := NewJSONEvent(event, v)
+	if err != nil {
+		return err
+	}
+
+	return broker.Publish(event)
+}
+
+// PublishJSON publishes the event to the topic with its data set to v encoded
+// as JSON.
+func (hub *Hub) PublishJSON(topic string, event Event, v any) error {
+	event, err := NewJSONEvent(event, v)
+	if err != nil {
+		return err
+	}
+
+	return hub.Publish(topic, event)
+}
+
+// TypedEvent is an event read by a TypedSource, along with its decoded data.
+type TypedEvent[T any] struct {
+	Event
+
+	// Value is the event's decoded data.
+	Value T
+
+	// Err is the *DecodeError if the data could not be decoded, in which case
+	// Value is the zero value.
+	Err error
+}
+
+// TypedSource reads events from an EventSource and decodes their data as JSON
+// into a T.
+type TypedSource[T any] struct {
+	source *EventSource
+}
+
+// NewTypedSource returns a TypedSource which reads from the source.
+func NewTypedSource[T any](source *EventSource) *TypedSource[T] {
+	return &TypedSource[T]{source: source}
+}
+
+// Next reads and decodes the next event. An event which cannot be decoded is
+// still returned, with its Err set, and does not end the stream.
+func (typed *TypedSource[T]) Next() (TypedEvent[T], error) {
+	return typed.NextContext(context.Background())
+}
+
+// NextContext is Next, but gives up when the context is cancelled, as with
+// EventSource.NextContext.
+func (typed *TypedSource[T]) NextContext(ctx context.Context) (TypedEvent[T], error) {
+	event, err := typed.source.NextContext(ctx)
+	if err != nil {
+		return TypedEvent[T]{}, err
+	}
+
+	value, err := DecodeJSON[T](event)
+
+	return TypedEvent[T]{
+		Event: event,
+		Value: value,
+		Err:   err,
+	}, nil
+}
+
+// Events returns an iterator over the decoded events, as with
+// EventSource.Events. Events which cannot be decoded are yielded with their
+// Err set, and do not end the iteration.
+func (typed *TypedSource[T]) Events(ctx context.Context) iter.Seq2[TypedEvent[T], error] {
+	return func(yield func(TypedEvent[T], error) bool) {
+		defer typed.source.Close()
+
+		for {
+			event, err := typed.NextContext(ctx)
+			if err == io.EOF {
+				return
+			}
+
+			if err != nil {
+				yield(TypedEvent[T]{}, err)
+				return
+			}
+
+			if !yield(event, nil) {
+				return
+			}
+		}
+	}
+}
+
+// Close closes the underlying EventSource.
+func (typed *TypedSource[T]) Close() error {
+	return typed.source.Close()
+}
+
+// JSONTypes decodes the data of events into different types depending on
+// their names, as registered with RegisterJSON. The zero value is ready to
+// use, but is not safe for registering types concurrently with decoding.
+type JSONTypes struct {
+	decoders map[string]func(Event) (any, error)
+}
+
+// RegisterJSON registers T as the type of the data of events with the given
+// name. Events without a name are decoded as DefaultEventName.
+func RegisterJSON[T any](types *JSONTypes, name string) {
+	if types.decoders == nil {
+		types.decoders = map[string]func(Event) (any, error){}
+	}
+
+	types.decoders[name] = func(event Event) (any, error) {
+		value, err := DecodeJSON[T](event)
+		if err != nil {
+			return nil, err
+		}
+
+		return value, nil
+	}
+}
+
+// Decode decodes the event's data into the type registered for its name,
+// returning a value of that type. If no type is registered for the name,
+// ErrUnregisteredEvent is returned; if the data cannot be decoded, a
+// *DecodeError is returned.
+func (types *JSONTypes) Decode(event Event) (any, error) {
+	name := event.Name
+	if name == "" {
+		name = DefaultEventName
+	}
+
+	decode, found := types.decoders[name]
+	if !found {
+		return nil, fmt.Errorf("%w: %q", ErrUnregisteredEvent, name)
+	}
+
+	return decode(event)
+}
@@ -0,0 +1,169 @@
+package sse_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/vito/go-sse/sse"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type buildStarted struct {
+	Build int `json:"build"`
+}
+
+type logLine struct {
+	Line string `json:"line"`
+}
+
+var _ = Describe("JSON", func() {
+	Describe("DecodeJSON", func() {
+		It("decodes the event's data", func() {
+			Ω(DecodeJSON[buildStarted](Event{Data: []byte(`{"build":42}`)})).Should(Equal(buildStarted{Build: 42}))
+		})
+
+		It("returns a DecodeError for invalid data", func() {
+			event := Event{ID: "1", Data: []byte(`{"build":"forty-two"}`)}
+
+			_, err := DecodeJSON[buildStarted](event)
+
+			var decodeErr *DecodeError
+			Ω(errors.As(err, &decodeErr)).Should(BeTrue())
+			Ω(decodeErr.Event).Should(Equal(event))
+
+			var typeErr *json.UnmarshalTypeError
+			Ω(errors.As(err, &typeErr)).Should(BeTrue())
+		})
+	})
+
+	Describe("Encoder.EncodeJSON", func() {
+		It("writes the event with the value as its data", func() {
+			var buffer bytes.Buffer
+			Ω(NewEncoder(&buffer).EncodeJSON(Event{ID: "1", Name: "build"}, buildStarted{Build: 42})).Should(Succeed())
+			Ω(buffer.String()).Should(Equal("id: 1\nevent: build\ndata: {\"build\":42}\n\n"))
+		})
+
+		It("fails for values which cannot be encoded", func() {
+			var buffer bytes.Buffer
+			Ω(NewEncoder(&buffer).EncodeJSON(Event{}, make(chan int))).ShouldNot(Succeed())
+			Ω(buffer.Len()).Should(BeZero())
+		})
+	})
+
+	Describe("publishing and reading typed events", func() {
+		var (
+			broker *Broker
+			server *httptest.Server
+
+			source *EventSource
+		)
+
+		BeforeEach(func() {
+			broker = &Broker{}
+			server = httptest.NewServer(broker)
+
+			var err error
+			source, err = Connect(http.DefaultClient, 100*time.Millisecond, func() *http.Request {
+				request, err := http.NewRequest("GET", server.URL, nil)
+				Ω(err).ShouldNot(HaveOccurred())
+				return request
+			})
+			Ω(err).ShouldNot(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			source.Close()
+			server.Close()
+		})
+
+		It("decodes each event, reporting decode errors without ending the stream", func() {
+			typed := NewTypedSource[buildStarted](source)
+
+			Ω(broker.PublishJSON(Event{ID: "1"}, buildStarted{Build: 1})).Should(Succeed())
+			Ω(broker.Publish(Event{ID: "2", Data: []byte("not json")})).Should(Succeed())
+			Ω(broker.PublishJSON(Event{ID: "3"}, buildStarted{Build: 3})).Should(Succeed())
+
+			event, err := typed.Next()
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(event.ID).Should(Equal("1"))
+			Ω(event.Value).Should(Equal(buildStarted{Build: 1}))
+			Ω(event.Err).ShouldNot(HaveOccurred())
+
+			event, err = typed.Next()
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(event.ID).Should(Equal("2"))
+
+			var decodeErr *DecodeError
+			Ω(errors.As(event.Err, &decodeErr)).Should(BeTrue())
+
+			event, err = typed.Next()
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(event.ID).Should(Equal("3"))
+			Ω(event.Value).Should(Equal(buildStarted{Build: 3}))
+		})
+
+		It("iterates over the decoded events", func() {
+			typed := NewTypedSource[buildStarted](source)
+
+			Ω(broker.PublishJSON(Event{ID: "1"}, buildStarted{Build: 1})).Should(Succeed())
+			Ω(broker.PublishJSON(Event{ID: "2"}, buildStarted{Build: 2})).Should(Succeed())
+
+			var builds []int
+			for event, err := range typed.Events(context.Background()) {
+				Ω(err).ShouldNot(HaveOccurred())
+
+				builds = append(builds, event.Value.Build)
+				if len(builds) == 2 {
+					break
+				}
+			}
+
+			Ω(builds).Should(Equal([]int{1, 2}))
+			Ω(source.ReadyState()).Should(Equal(Closed))
+		})
+
+		It("fails to publish values which cannot be encoded", func() {
+			Ω(broker.PublishJSON(Event{}, make(chan int))).ShouldNot(Succeed())
+		})
+	})
+
+	Describe("JSONTypes", func() {
+		var types *JSONTypes
+
+		BeforeEach(func() {
+			types = &JSONTypes{}
+			RegisterJSON[buildStarted](types, "build")
+			RegisterJSON[logLine](types, DefaultEventName)
+		})
+
+		It("decodes each event into the type registered for its name", func() {
+			Ω(types.Decode(Event{Name: "build", Data: []byte(`{"build":42}`)})).Should(Equal(buildStarted{Build: 42}))
+			Ω(types.Decode(Event{Name: "message", Data: []byte(`{"line":"hi"}`)})).Should(Equal(logLine{Line: "hi"}))
+		})
+
+		It("decodes events without a name as the default name", func() {
+			Ω(types.Decode(Event{Data: []byte(`{"line":"hi"}`)})).Should(Equal(logLine{Line: "hi"}))
+		})
+
+		It("fails for unregistered names", func() {
+			_, err := types.Decode(Event{Name: "alert", Data: []byte(`{}`)})
+			Ω(err).Should(MatchError(ErrUnregisteredEvent))
+			Ω(err).Should(MatchError(`no type registered for event: "alert"`))
+		})
+
+		It("returns a DecodeError for invalid data", func() {
+			value, err := types.Decode(Event{Name: "build", Data: []byte(`nope`)})
+			Ω(value).Should(BeNil())
+
+			var decodeErr *DecodeError
+			Ω(errors.As(err, &decodeErr)).Should(BeTrue())
+		})
+	})
+})
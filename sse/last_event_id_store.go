@@ -0,0 +1,112 @@
+package sse
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// LastEventIDStore persists the ID of the last event processed from an
+// EventSource, so that a restarted process can resume the stream where it
+// left off rather than from scratch.
+type LastEventIDStore interface {
+	// Load returns the saved ID, or an empty string if none has been saved.
+	Load() (string, error)
+
+	// Save records the ID, replacing any previously saved.
+	Save(id string) error
+}
+
+// MemoryLastEventIDStore is a LastEventIDStore which keeps the ID in memory,
+// e.g. for sharing it between EventSources within a process, or in tests. The
+// zero value is ready to use.
+type MemoryLastEventIDStore struct {
+	lock sync.Mutex
+	id   string
+}
+
+func (store *MemoryLastEventIDStore) Load() (string, error) {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	return store.id, nil
+}
+
+func (store *MemoryLastEventIDStore) Save(id string) error {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	store.id = id
+
+	return nil
+}
+
+// FileLastEventIDStore is a LastEventIDStore which keeps the ID in a file.
+// Each save replaces the file atomically, so a crash mid-save leaves the
+// previous ID in place.
+type FileLastEventIDStore struct {
+	path string
+
+	lock sync.Mutex
+}
+
+// NewFileLastEventIDStore returns a store which keeps the ID in the file at
+// the given path. The file and its directory are created on the first save.
+func NewFileLastEventIDStore(path string) *FileLastEventIDStore {
+	return &FileLastEventIDStore{
+		path: path,
+	}
+}
+
+func (store *FileLastEventIDStore) Load() (string, error) {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	id, err := os.ReadFile(store.path)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	return string(id), nil
+}
+
+func (store *FileLastEventIDStore) Save(id string) error {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	dir := filepath.Dir(store.path)
+
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, filepath.Base(store.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+
+	_, err = tmp.WriteString(id)
+	if err == nil {
+		err = tmp.Sync()
+	}
+
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err == nil {
+		err = os.Rename(tmp.Name(), store.path)
+	}
+
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	return nil
+}
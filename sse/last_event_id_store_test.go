@@ -0,0 +1,81 @@
+package sse_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/vito/go-sse/sse"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("MemoryLastEventIDStore", func() {
+	var store *MemoryLastEventIDStore
+
+	BeforeEach(func() {
+		store = &MemoryLastEventIDStore{}
+	})
+
+	It("starts out empty", func() {
+		Ω(store.Load()).Should(BeEmpty())
+	})
+
+	It("loads the last saved ID", func() {
+		Ω(store.Save("1")).Should(Succeed())
+		Ω(store.Save("2")).Should(Succeed())
+		Ω(store.Load()).Should(Equal("2"))
+	})
+})
+
+var _ = Describe("FileLastEventIDStore", func() {
+	var (
+		dir   string
+		path  string
+		store *FileLastEventIDStore
+	)
+
+	BeforeEach(func() {
+		dir = GinkgoT().TempDir()
+		path = filepath.Join(dir, "state", "last-event-id")
+		store = NewFileLastEventIDStore(path)
+	})
+
+	Context("when nothing has been saved", func() {
+		It("loads an empty ID", func() {
+			Ω(store.Load()).Should(BeEmpty())
+		})
+	})
+
+	Context("when an ID has been saved", func() {
+		BeforeEach(func() {
+			Ω(store.Save("1")).Should(Succeed())
+			Ω(store.Save("2")).Should(Succeed())
+		})
+
+		It("loads it", func() {
+			Ω(store.Load()).Should(Equal("2"))
+		})
+
+		It("persists it for other stores using the same file", func() {
+			Ω(NewFileLastEventIDStore(path).Load()).Should(Equal("2"))
+		})
+
+		It("does not leave temporary files behind", func() {
+			entries, err := os.ReadDir(filepath.Dir(path))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(entries).Should(HaveLen(1))
+			Ω(entries[0].Name()).Should(Equal("last-event-id"))
+		})
+	})
+
+	Context("when the file cannot be written", func() {
+		BeforeEach(func() {
+			Ω(os.WriteFile(filepath.Join(dir, "state"), nil, 0644)).Should(Succeed())
+		})
+
+		It("fails to save", func() {
+			Ω(store.Save("1")).ShouldNot(Succeed())
+		})
+	})
+})
@@ -0,0 +1,149 @@
+package sse
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+)
+
+var errLineTooLong = errors.New("line too long")
+
+var byteOrderMark = []byte("\uFEFF")
+
+// lineReader splits a stream into lines terminated by CR, LF, or CRLF, as
+// required by the spec. bufio.Reader.ReadLine only handles the latter two.
+// A byte order mark at the start of the stream is skipped.
+type lineReader struct {
+	buf *bufio.Reader
+
+	// maximum length of a line, or 0 for no limit; when exceeded, the rest of
+	// the line is discarded if discardLong is set
+	maxLength   int
+	discardLong bool
+
+	started bool
+
+	// number of the last line read, and the offset in the stream at which it
+	// started
+	number int
+	offset int64
+
+	// total bytes consumed from the stream
+	consumed int64
+
+	// line is reused between calls
+	line []byte
+
+	// the previous line ended in CR, so an LF immediately following it belongs
+	// to the same line ending, even if it arrives in a later read
+	skipLF bool
+}
+
+func newLineReader(buf *bufio.Reader) *lineReader {
+	return &lineReader{buf: buf}
+}
+
+// ReadLine returns the next line, without its line ending. The returned slice
+// is only valid until the next call. A partial line at the end of the stream
+// is discarded, and the error is returned.
+//
+// If the line exceeds the maximum length, errLineTooLong is returned, either
+// immediately or once the rest of the line has been discarded.
+func (reader *lineReader) ReadLine() ([]byte, error) {
+	reader.line = reader.line[:0]
+
+	if !reader.started {
+		reader.started = true
+
+		err := reader.skipByteOrderMark()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	reader.number++
+	reader.offset = reader.consumed
+
+	tooLong := false
+
+	for {
+		// block until something is buffered, then consume all of it that we can
+		if reader.buf.Buffered() == 0 {
+			_, err := reader.buf.Peek(1)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		chunk, _ := reader.buf.Peek(reader.buf.Buffered())
+
+		if reader.skipLF {
+			reader.skipLF = false
+
+			if chunk[0] == '\n' {
+				reader.discard(1)
+				reader.offset++
+				continue
+			}
+		}
+
+		end := bytes.IndexAny(chunk, "\r\n")
+
+		content := chunk
+		if end != -1 {
+			content = chunk[:end]
+		}
+
+		if !tooLong && reader.maxLength > 0 && len(reader.line)+len(content) > reader.maxLength {
+			if !reader.discardLong {
+				return nil, errLineTooLong
+			}
+
+			tooLong = true
+			reader.line = reader.line[:0]
+		}
+
+		if !tooLong {
+			reader.line = append(reader.line, content...)
+		}
+
+		if end == -1 {
+			reader.discard(len(chunk))
+			continue
+		}
+
+		reader.skipLF = chunk[end] == '\r'
+		reader.discard(end + 1)
+
+		if tooLong {
+			return nil, errLineTooLong
+		}
+
+		return reader.line, nil
+	}
+}
+
+func (reader *lineReader) skipByteOrderMark() error {
+	// only peek further if it looks like one, so that we don't block waiting
+	// for a short stream to send more
+	first, err := reader.buf.Peek(1)
+	if err != nil {
+		return err
+	}
+
+	if first[0] != byteOrderMark[0] {
+		return nil
+	}
+
+	prefix, err := reader.buf.Peek(len(byteOrderMark))
+	if err == nil && bytes.Equal(prefix, byteOrderMark) {
+		reader.discard(len(byteOrderMark))
+	}
+
+	return nil
+}
+
+func (reader *lineReader) discard(n int) {
+	reader.buf.Discard(n)
+	reader.consumed += int64(n)
+}
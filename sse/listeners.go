@@ -0,0 +1,95 @@
+package sse
+
+import (
+	"context"
+	"io"
+	"slices"
+	"sync/atomic"
+)
+
+// DefaultEventName is the name under which events without an event field are
+// dispatched, as in the browser's EventSource.
+const DefaultEventName = "message"
+
+// EventHandler handles an event dispatched by an EventSource.
+type EventHandler func(Event)
+
+type eventListener struct {
+	handler EventHandler
+	removed atomic.Bool
+}
+
+// AddEventListener registers a handler to be called by Dispatch for each event
+// with the given name. Events without a name are dispatched to listeners for
+// DefaultEventName.
+//
+// The returned function removes the listener. It may be called at any time,
+// including from within a handler while Dispatch is running, in which case
+// the listener is not called again, even for the event being dispatched.
+func (source *EventSource) AddEventListener(name string, handler EventHandler) func() {
+	listener := &eventListener{handler: handler}
+
+	source.listenersLock.Lock()
+	defer source.listenersLock.Unlock()
+
+	if source.listeners == nil {
+		source.listeners = map[string][]*eventListener{}
+	}
+
+	// listener slices are never modified in place, so that Dispatch can
+	// iterate over them without holding the lock
+	source.listeners[name] = append(slices.Clip(source.listeners[name]), listener)
+
+	return func() {
+		source.removeEventListener(name, listener)
+	}
+}
+
+// Dispatch reads events from the source and calls the listeners registered for
+// each event's name, in the order they were added, until the context is
+// cancelled or the source fails or is closed. It returns nil if the stream
+// ends.
+func (source *EventSource) Dispatch(ctx context.Context) error {
+	for {
+		event, err := source.NextContext(ctx)
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		name := event.Name
+		if name == "" {
+			name = DefaultEventName
+		}
+
+		source.listenersLock.RLock()
+		listeners := source.listeners[name]
+		source.listenersLock.RUnlock()
+
+		for _, listener := range listeners {
+			if !listener.removed.Load() {
+				listener.handler(event)
+			}
+		}
+	}
+}
+
+func (source *EventSource) removeEventListener(name string, listener *eventListener) {
+	listener.removed.Store(true)
+
+	source.listenersLock.Lock()
+	defer source.listenersLock.Unlock()
+
+	listeners := slices.DeleteFunc(slices.Clone(source.listeners[name]), func(l *eventListener) bool {
+		return l == listener
+	})
+
+	if len(listeners) == 0 {
+		delete(source.listeners, name)
+	} else {
+		source.listeners[name] = listeners
+	}
+}
@@ -0,0 +1,119 @@
+package sse_test
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	. "github.com/vito/go-sse/sse"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+)
+
+var _ = Describe("Event listeners", func() {
+	var (
+		server *ghttp.Server
+		source *EventSource
+	)
+
+	BeforeEach(func() {
+		server = ghttp.NewServer()
+
+		server.AppendHandlers(
+			func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Add("Content-Type", "text/event-stream; charset=utf-8")
+				w.WriteHeader(http.StatusOK)
+
+				Event{ID: "1", Data: []byte("unnamed")}.Write(w)
+				Event{ID: "2", Name: "build", Data: []byte("build 1")}.Write(w)
+				Event{ID: "3", Name: "log", Data: []byte("log line")}.Write(w)
+				Event{ID: "4", Name: "build", Data: []byte("build 2")}.Write(w)
+				Event{ID: "5", Name: "message", Data: []byte("named message")}.Write(w)
+			},
+		)
+
+		source = NewEventSource(http.DefaultClient, 100*time.Millisecond, func() *http.Request {
+			request, err := http.NewRequest("GET", server.URL(), nil)
+			Ω(err).ShouldNot(HaveOccurred())
+			return request
+		})
+	})
+
+	AfterEach(func() {
+		source.Close()
+		server.Close()
+	})
+
+	collect := func(events *[]Event) EventHandler {
+		return func(event Event) {
+			*events = append(*events, event)
+		}
+	}
+
+	idsOf := func(events []Event) []string {
+		var ids []string
+		for _, event := range events {
+			ids = append(ids, event.ID)
+		}
+		return ids
+	}
+
+	It("dispatches events to the listeners for their name until the stream ends", func() {
+		var builds, logs, messages []Event
+		source.AddEventListener("build", collect(&builds))
+		source.AddEventListener("log", collect(&logs))
+		source.AddEventListener(DefaultEventName, collect(&messages))
+
+		Ω(source.Dispatch(context.Background())).Should(Succeed())
+
+		Ω(idsOf(builds)).Should(Equal([]string{"2", "4"}))
+		Ω(idsOf(logs)).Should(Equal([]string{"3"}))
+		Ω(idsOf(messages)).Should(Equal([]string{"1", "5"}))
+	})
+
+	It("calls multiple listeners in the order they were added", func() {
+		var order []string
+		source.AddEventListener("log", func(Event) { order = append(order, "first") })
+		source.AddEventListener("log", func(Event) { order = append(order, "second") })
+
+		Ω(source.Dispatch(context.Background())).Should(Succeed())
+
+		Ω(order).Should(Equal([]string{"first", "second"}))
+	})
+
+	It("stops calling listeners once they are removed", func() {
+		var builds []Event
+
+		var removeBuilds func()
+		removeBuilds = source.AddEventListener("build", func(event Event) {
+			builds = append(builds, event)
+			removeBuilds()
+		})
+
+		Ω(source.Dispatch(context.Background())).Should(Succeed())
+
+		Ω(idsOf(builds)).Should(Equal([]string{"2"}))
+	})
+
+	It("does not call a listener removed by an earlier listener for the same event", func() {
+		var called bool
+
+		var removeSecond func()
+		source.AddEventListener("log", func(Event) { removeSecond() })
+		removeSecond = source.AddEventListener("log", func(Event) { called = true })
+
+		Ω(source.Dispatch(context.Background())).Should(Succeed())
+
+		Ω(called).Should(BeFalse())
+	})
+
+	It("returns the context's error when it is cancelled", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+
+		source.AddEventListener("build", func(Event) { cancel() })
+
+		Ω(source.Dispatch(ctx)).Should(Equal(context.Canceled))
+	})
+})
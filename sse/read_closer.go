@@ -5,118 +5,301 @@ import (
 	"bytes"
 	"errors"
 	"io"
-	"strconv"
+	"iter"
+	"math"
+	"sync/atomic"
 	"time"
 )
 
 type ReadCloser struct {
-	lastID string
+	// the spec's last event ID buffer, which is updated as soon as an id field
+	// is read, and the ID as of the last dispatch
+	lastID       string
+	dispatchedID string
 
-	buf         *bufio.Reader
+	// the name of the last event, reused to avoid allocating it again when
+	// it repeats
+	lastName string
+
+	// the data buffer, reused between events
+	data []byte
+
+	// a limit was exceeded partway through an event, so the stream cannot be
+	// parsed any further; returned by every later call
+	tooLarge error
+
+	options ReaderOptions
+
+	lines       *lineReader
+	replaced    []byte
 	closeSource func() error
-	closed      bool
+	closed      atomic.Bool
 }
 
 func NewReadCloser(source io.ReadCloser) *ReadCloser {
+	return NewReadCloserWithOptions(source, ReaderOptions{})
+}
+
+func NewReadCloserWithOptions(source io.ReadCloser, options ReaderOptions) *ReadCloser {
+	lines := newLineReader(bufio.NewReader(source))
+	lines.maxLength = options.MaxLineLength
+	lines.discardLong = options.SkipOversizedEvents
+
 	return &ReadCloser{
-		closeSource: func() error { return source.Close() },
-		buf:         bufio.NewReader(source),
+		lastID:       options.LastEventID,
+		dispatchedID: options.LastEventID,
+		options:      options,
+		closeSource:  func() error { return source.Close() },
+		lines:        lines,
 	}
 }
 
 var alreadyClosedError = errors.New("ReadCloser already closed")
 
 func (rc *ReadCloser) Close() error {
-	if rc.closed {
+	if !rc.closed.CompareAndSwap(false, true) {
 		return alreadyClosedError
 	}
 
-	rc.closed = true
-
 	return rc.closeSource()
 }
 
+// All returns an iterator over the remaining events. Iteration stops at EOF;
+// any other error is yielded and ends the iteration. The ReadCloser is closed
+// once iteration stops, including when the loop breaks early.
+func (rc *ReadCloser) All() iter.Seq2[Event, error] {
+	return func(yield func(Event, error) bool) {
+		defer rc.Close()
+
+		for {
+			event, err := rc.Next()
+			if err == io.EOF {
+				return
+			}
+
+			if err != nil {
+				yield(Event{}, err)
+				return
+			}
+
+			if !yield(event, nil) {
+				return
+			}
+		}
+	}
+}
+
+// LastEventID returns the ID which the stream had set as of the last blank
+// line read, i.e. the spec's last event ID string. Unlike the ID of the last
+// event returned by Next, this accounts for IDs set by blocks without data,
+// which are not dispatched as events.
+func (rc *ReadCloser) LastEventID() string {
+	return rc.dispatchedID
+}
+
 func (rc *ReadCloser) Next() (Event, error) {
+	if rc.tooLarge != nil {
+		return Event{}, rc.tooLarge
+	}
+
 	var event Event
 
-	// event ID defaults to last ID per the spec
-	event.ID = rc.lastID
+	rc.data = rc.data[:0]
+
+	// size of the event so far, for enforcing limits
+	var fields, buffered int
+
+	// the event exceeded a limit and is being skipped
+	skipping := false
 
-	// if an empty id is explicitly given, it sets the value and resets the last
-	// id; track its presence with a bool to distinguish between zero-value
-	idPresent := false
+	// exceeded either fails or starts skipping the rest of the event
+	exceeded := func(limit string) error {
+		if !rc.options.SkipOversizedEvents {
+			rc.tooLarge = EventTooLargeError{
+				Limit: limit,
+				Line:  rc.lines.number,
+			}
+
+			return rc.tooLarge
+		}
+
+		// the id and retry which preceded the offending line still take
+		// effect, like those of an event without data
+		event = Event{Retry: event.Retry}
+		rc.data = rc.data[:0]
+		skipping = true
+
+		return nil
+	}
 
-	prefix := []byte{}
 	for {
-		line, isPrefix, err := rc.buf.ReadLine()
+		line, err := rc.lines.ReadLine()
+		if err == errLineTooLong {
+			if err := exceeded("MaxLineLength"); err != nil {
+				return Event{}, err
+			}
+
+			continue
+		}
+
 		if err != nil {
 			return Event{}, err
 		}
 
-		line = append(prefix, line...)
+		if rc.options.OnLine != nil {
+			rc.options.OnLine(line)
+		}
 
-		if isPrefix {
-			prefix = line
-			continue
-		} else {
-			prefix = []byte{}
+		if invalid := invalidUTF8(line); invalid != -1 {
+			switch rc.options.InvalidUTF8 {
+			case ReplaceInvalidUTF8:
+				rc.replaced = replaceInvalidUTF8(rc.replaced[:0], line)
+				line = rc.replaced
+			case RejectInvalidUTF8:
+				return Event{}, InvalidUTF8Error{
+					Line:   rc.lines.number,
+					Offset: rc.lines.offset + int64(invalid),
+				}
+			}
 		}
 
 		// empty line; dispatch event
 		if len(line) == 0 {
-			if len(event.Data) == 0 {
-				// event had no data; skip it per the spec
+			rc.dispatchedID = rc.lastID
+
+			fields, buffered = 0, 0
+
+			if skipping {
+				skipping = false
 				continue
 			}
 
-			if idPresent {
-				// record last ID
-				rc.lastID = event.ID
+			if len(rc.data) == 0 {
+				// event had no data; skip it per the spec, forgetting its name
+				// but not its retry, which takes effect regardless
+				event.Name = ""
+				event.Fields = nil
+				continue
 			}
 
+			// event ID defaults to last ID per the spec, and an empty id
+			// field resets it
+			event.ID = rc.lastID
+			event.ResetID = event.ResetID && event.ID == ""
+
 			// trim terminating linebreak
-			event.Data = event.Data[0 : len(event.Data)-1]
+			event.Data = rc.data[:len(rc.data)-1]
+			if !rc.options.ReuseData {
+				event.Data = bytes.Clone(event.Data)
+			}
 
 			// dispatch event
 			return event, nil
 		}
 
 		if line[0] == ':' {
-			// comment; skip
+			if rc.options.OnComment != nil {
+				comment := line[1:]
+				if len(comment) > 0 && comment[0] == ' ' {
+					comment = comment[1:]
+				}
+
+				rc.options.OnComment(string(comment))
+			}
+
 			continue
 		}
 
-		var field, value string
-
-		segments := bytes.SplitN(line, []byte(":"), 2)
-		if len(segments) == 1 {
-			// line with no colon is just the field, with empty value
-			field = string(segments[0])
-		} else {
-			field = string(segments[0])
-			value = string(segments[1])
+		if skipping {
+			// oversized event; skip
+			continue
 		}
 
-		if len(value) > 0 {
+		field, value, found := bytes.Cut(line, []byte(":"))
+		if found && len(value) > 0 && value[0] == ' ' {
 			// trim only a single leading space
-			if value[0] == ' ' {
-				value = value[1:]
+			value = value[1:]
+		}
+
+		fields++
+		if rc.options.MaxFields > 0 && fields > rc.options.MaxFields {
+			if err := exceeded("MaxFields"); err != nil {
+				return Event{}, err
 			}
+
+			continue
+		}
+
+		buffered += len(value)
+		if rc.options.MaxBufferedBytes > 0 && buffered > rc.options.MaxBufferedBytes {
+			if err := exceeded("MaxBufferedBytes"); err != nil {
+				return Event{}, err
+			}
+
+			continue
 		}
 
-		switch field {
+		// converting the field name in the switch does not allocate, and the
+		// values are only converted to strings when they change
+		switch string(field) {
 		case "id":
-			idPresent = true
-			event.ID = value
+			// the spec requires ids containing NULL to be ignored
+			if string(value) != rc.lastID && bytes.IndexByte(value, 0) == -1 {
+				rc.lastID = string(value)
+			}
+
+			event.ResetID = len(value) == 0
 		case "event":
-			event.Name = value
+			if string(value) != rc.lastName {
+				rc.lastName = string(value)
+			}
+
+			event.Name = rc.lastName
 		case "data":
-			event.Data = append(event.Data, []byte(value+"\n")...)
+			if rc.options.MaxEventSize > 0 && len(rc.data)+len(value) > rc.options.MaxEventSize {
+				if err := exceeded("MaxEventSize"); err != nil {
+					return Event{}, err
+				}
+
+				continue
+			}
+
+			rc.data = append(rc.data, value...)
+			rc.data = append(rc.data, '\n')
 		case "retry":
-			retryInMS, err := strconv.Atoi(value)
-			if err == nil {
+			retryInMS, ok := parseDigits(value)
+			if ok {
 				event.Retry = time.Duration(retryInMS) * time.Millisecond
 			}
+		default:
+			if rc.options.KeepUnknownFields {
+				event.Fields = append(event.Fields, Field{
+					Name:  string(field),
+					Value: string(value),
+				})
+			}
 		}
 	}
 }
+
+// parseDigits parses a retry value, which the spec requires to consist only
+// of ASCII digits.
+func parseDigits(value []byte) (int64, bool) {
+	if len(value) == 0 {
+		return 0, false
+	}
+
+	var n int64
+	for _, c := range value {
+		if c < '0' || c > '9' {
+			return 0, false
+		}
+
+		n = n*10 + int64(c-'0')
+		if n > math.MaxInt64/int64(time.Millisecond) {
+			return 0, false
+		}
+	}
+
+	return n, true
+}
@@ -0,0 +1,55 @@
+package sse_test
+
+import (
+	"io"
+	"testing"
+
+	. "github.com/vito/go-sse/sse"
+)
+
+// loopReader repeats a stream forever, so that each iteration of a benchmark
+// reads a single event.
+type loopReader struct {
+	stream []byte
+	offset int
+}
+
+func (reader *loopReader) Read(p []byte) (int, error) {
+	n := copy(p, reader.stream[reader.offset:])
+	reader.offset = (reader.offset + n) % len(reader.stream)
+	return n, nil
+}
+
+func benchmarkNext(b *testing.B, stream string, options ReaderOptions) {
+	readCloser := NewReadCloserWithOptions(io.NopCloser(&loopReader{stream: []byte(stream)}), options)
+
+	b.SetBytes(int64(len(stream)))
+	b.ReportAllocs()
+
+	for b.Loop() {
+		_, err := readCloser.Next()
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+const smallEvent = "id: 12345\nevent: update\ndata: {\"status\":\"ok\",\"count\":42}\n\n"
+
+const multilineEvent = "id: 12345\nevent: log\ndata: first line of output\ndata: second line of output\ndata: third line of output\nretry: 1000\n\n"
+
+func BenchmarkNextSmall(b *testing.B) {
+	benchmarkNext(b, smallEvent, ReaderOptions{})
+}
+
+func BenchmarkNextSmallReusingData(b *testing.B) {
+	benchmarkNext(b, smallEvent, ReaderOptions{ReuseData: true})
+}
+
+func BenchmarkNextMultiline(b *testing.B) {
+	benchmarkNext(b, multilineEvent, ReaderOptions{})
+}
+
+func BenchmarkNextMultilineReusingData(b *testing.B) {
+	benchmarkNext(b, multilineEvent, ReaderOptions{ReuseData: true})
+}
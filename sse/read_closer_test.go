@@ -1,8 +1,10 @@
 package sse_test
 
 import (
+	"errors"
 	"io"
 	"strings"
+	"testing/iotest"
 	"time"
 
 	. "github.com/vito/go-sse/sse"
@@ -86,6 +88,167 @@ var _ = Describe("readCloser", func() {
 			})
 		})
 
+		Context("when CR is used as a line ending", func() {
+			BeforeEach(func() {
+				eventStream += ":foo bar baz\rid: 123\revent: some-event\rdata: hello\rdata: world\r\r"
+			})
+
+			It("properly splits on it", func() {
+				event, err := readCloser.Next()
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(event).Should(Equal(Event{
+					ID:   "123",
+					Name: "some-event",
+					Data: []byte("hello\nworld"),
+				}))
+			})
+		})
+
+		Context("when line endings are mixed", func() {
+			BeforeEach(func() {
+				eventStream += "id: 1\rdata: a\r\ndata: b\ndata: c\r\r\n"
+				eventStream += "id: 2\ndata: d\r\n\r"
+				eventStream += "id: 3\r\ndata: e\n\n"
+			})
+
+			It("treats CR, LF, and CRLF alike", func() {
+				Ω(readCloser.Next()).Should(Equal(Event{ID: "1", Data: []byte("a\nb\nc")}))
+				Ω(readCloser.Next()).Should(Equal(Event{ID: "2", Data: []byte("d")}))
+				Ω(readCloser.Next()).Should(Equal(Event{ID: "3", Data: []byte("e")}))
+			})
+		})
+
+		Context("when the stream arrives one byte at a time", func() {
+			BeforeEach(func() {
+				readCloser = NewReadCloser(io.NopCloser(iotest.OneByteReader(strings.NewReader(
+					"id: 1\r\ndata: a\r\ndata: b\r\n\r\nid: 2\rdata: c\r\r",
+				))))
+			})
+
+			It("splits CRLF across reads as a single line ending", func() {
+				Ω(readCloser.Next()).Should(Equal(Event{ID: "1", Data: []byte("a\nb")}))
+				Ω(readCloser.Next()).Should(Equal(Event{ID: "2", Data: []byte("c")}))
+			})
+		})
+
+		Context("when a CR ends one write and its LF begins the next", func() {
+			var writer *io.PipeWriter
+
+			BeforeEach(func() {
+				var reader *io.PipeReader
+				reader, writer = io.Pipe()
+
+				readCloser = NewReadCloser(reader)
+
+				go func() {
+					defer GinkgoRecover()
+
+					_, err := writer.Write([]byte("data: a\r"))
+					Ω(err).ShouldNot(HaveOccurred())
+
+					_, err = writer.Write([]byte("\ndata: b\r"))
+					Ω(err).ShouldNot(HaveOccurred())
+
+					_, err = writer.Write([]byte("\n\r"))
+					Ω(err).ShouldNot(HaveOccurred())
+
+					_, err = writer.Write([]byte("\n"))
+					Ω(err).ShouldNot(HaveOccurred())
+
+					writer.Close()
+				}()
+			})
+
+			It("does not treat the LF as an empty line", func() {
+				Ω(readCloser.Next()).Should(Equal(Event{Data: []byte("a\nb")}))
+
+				_, err := readCloser.Next()
+				Ω(err).Should(Equal(io.EOF))
+			})
+		})
+
+		Context("when the stream begins with a byte order mark", func() {
+			BeforeEach(func() {
+				eventStream = "\uFEFFid: 1\ndata: a\n\n\uFEFFdata: b\ndata: c\n\n"
+			})
+
+			It("ignores it at the start of the stream only", func() {
+				Ω(readCloser.Next()).Should(Equal(Event{ID: "1", Data: []byte("a")}))
+				Ω(readCloser.Next()).Should(Equal(Event{ID: "1", Data: []byte("c")}))
+			})
+		})
+
+		Context("when the stream is only a byte order mark", func() {
+			BeforeEach(func() {
+				readCloser = NewReadCloser(io.NopCloser(strings.NewReader("\uFEFF")))
+			})
+
+			It("returns EOF", func() {
+				_, err := readCloser.Next()
+				Ω(err).Should(Equal(io.EOF))
+			})
+		})
+
+		Context("when the stream contains invalid UTF-8", func() {
+			var (
+				stream  string
+				options ReaderOptions
+			)
+
+			BeforeEach(func() {
+				stream = "data: ok\n\nid: 1\ndata: a\xffb\xc3\n\n"
+				options = ReaderOptions{}
+			})
+
+			JustBeforeEach(func() {
+				readCloser = NewReadCloserWithOptions(io.NopCloser(strings.NewReader(stream)), options)
+			})
+
+			It("replaces each invalid byte with U+FFFD by default", func() {
+				Ω(readCloser.Next()).Should(Equal(Event{Data: []byte("ok")}))
+				Ω(readCloser.Next()).Should(Equal(Event{ID: "1", Data: []byte("a\uFFFDb\uFFFD")}))
+			})
+
+			Context("when configured to pass it through", func() {
+				BeforeEach(func() {
+					options.InvalidUTF8 = PassInvalidUTF8
+				})
+
+				It("leaves the bytes as they are", func() {
+					Ω(readCloser.Next()).Should(Equal(Event{Data: []byte("ok")}))
+					Ω(readCloser.Next()).Should(Equal(Event{ID: "1", Data: []byte("a\xffb\xc3")}))
+				})
+			})
+
+			Context("when configured to reject it", func() {
+				BeforeEach(func() {
+					options.InvalidUTF8 = RejectInvalidUTF8
+				})
+
+				It("returns an error with the position of the first invalid byte", func() {
+					Ω(readCloser.Next()).Should(Equal(Event{Data: []byte("ok")}))
+
+					_, err := readCloser.Next()
+					Ω(err).Should(Equal(InvalidUTF8Error{Line: 4, Offset: 23}))
+					Ω(err).Should(MatchError("invalid UTF-8 in event stream at line 4 (offset 23)"))
+				})
+			})
+
+			Context("when the stream begins with a byte order mark", func() {
+				BeforeEach(func() {
+					stream = "\uFEFF" + stream
+					options.InvalidUTF8 = RejectInvalidUTF8
+				})
+
+				It("counts it in the offset", func() {
+					Ω(readCloser.Next()).Should(Equal(Event{Data: []byte("ok")}))
+
+					_, err := readCloser.Next()
+					Ω(err).Should(Equal(InvalidUTF8Error{Line: 4, Offset: 26}))
+				})
+			})
+		})
+
 		Context("when an event comes on the stream", func() {
 			Context("with an event id specified", func() {
 				BeforeEach(func() {
@@ -161,19 +324,61 @@ id
 `
 						})
 
-						It("returns an event with an empty id", func() {
+						It("returns an event which resets the id", func() {
 							event, err := readCloser.Next()
 							Ω(err).ShouldNot(HaveOccurred())
 							Ω(event).Should(Equal(Event{
-								ID:   "",
-								Name: "some-other-event",
-								Data: []byte("hello again"),
+								ID:      "",
+								ResetID: true,
+								Name:    "some-other-event",
+								Data:    []byte("hello again"),
 							}))
 						})
 					})
 				})
 			})
 
+			Context("with an id containing NULL", func() {
+				BeforeEach(func() {
+					eventStream += "id: 1\ndata: a\n\nid: 2\x003\ndata: b\n\n"
+				})
+
+				It("ignores the id", func() {
+					Ω(readCloser.Next()).Should(Equal(Event{ID: "1", Data: []byte("a")}))
+					Ω(readCloser.Next()).Should(Equal(Event{ID: "1", Data: []byte("b")}))
+				})
+			})
+
+			Context("with an id but no data", func() {
+				BeforeEach(func() {
+					eventStream += "id: 1\ndata: a\n\nid: 2\nevent: ignored\n\ndata: b\n\n"
+				})
+
+				It("applies the id to the following events, but not the name", func() {
+					Ω(readCloser.Next()).Should(Equal(Event{ID: "1", Data: []byte("a")}))
+					Ω(readCloser.LastEventID()).Should(Equal("1"))
+
+					Ω(readCloser.Next()).Should(Equal(Event{ID: "2", Data: []byte("b")}))
+					Ω(readCloser.LastEventID()).Should(Equal("2"))
+				})
+			})
+
+			Context("with an id which is not yet dispatched", func() {
+				BeforeEach(func() {
+					eventStream += "id: 1\ndata: a\n\nid: 2\n"
+				})
+
+				It("does not report it as the last event ID", func() {
+					Ω(readCloser.Next()).Should(Equal(Event{ID: "1", Data: []byte("a")}))
+
+					go buffer.Close()
+
+					_, err := readCloser.Next()
+					Ω(err).Should(Equal(io.EOF))
+					Ω(readCloser.LastEventID()).Should(Equal("1"))
+				})
+			})
+
 			Context("and it sets a retry time", func() {
 				BeforeEach(func() {
 					eventStream += `id: 12
@@ -194,6 +399,18 @@ data: hello
 				})
 			})
 
+			Context("and it sets a retry time which is not only digits", func() {
+				BeforeEach(func() {
+					eventStream += "retry: -100\nretry: 1e3\nretry: 100ms\ndata: hello\n\n"
+				})
+
+				It("ignores it", func() {
+					Ω(readCloser.Next()).Should(Equal(Event{
+						Data: []byte("hello"),
+					}))
+				})
+			})
+
 			Context("but is not properly terminated", func() {
 				BeforeEach(func() {
 					eventStream += `id: 12
@@ -306,6 +523,356 @@ data
 		})
 	})
 
+	Describe("limits", func() {
+		var options ReaderOptions
+
+		BeforeEach(func() {
+			options = ReaderOptions{}
+		})
+
+		next := func(stream string) (Event, error) {
+			return NewReadCloserWithOptions(io.NopCloser(strings.NewReader(stream)), options).Next()
+		}
+
+		Context("with a maximum line length", func() {
+			BeforeEach(func() {
+				options.MaxLineLength = 10
+			})
+
+			It("allows lines up to the limit", func() {
+				Ω(next("data: 1234\n\n")).Should(Equal(Event{Data: []byte("1234")}))
+			})
+
+			It("fails on longer lines", func() {
+				_, err := next("id: 1\ndata: 12345\n\n")
+				Ω(err).Should(Equal(EventTooLargeError{Limit: "MaxLineLength", Line: 2}))
+				Ω(errors.Is(err, ErrEventTooLarge)).Should(BeTrue())
+				Ω(err).Should(MatchError("event too large: exceeded MaxLineLength at line 2"))
+			})
+
+			It("fails without waiting for the end of the line", func() {
+				reader, writer := io.Pipe()
+				defer writer.Close()
+
+				go writer.Write([]byte("data: 1234567890"))
+
+				_, err := NewReadCloserWithOptions(reader, options).Next()
+				Ω(errors.Is(err, ErrEventTooLarge)).Should(BeTrue())
+			})
+
+			Context("when the line spans more than one read", func() {
+				BeforeEach(func() {
+					options.MaxLineLength = 5000
+				})
+
+				It("does not parse the rest of it", func() {
+					readCloser := NewReadCloserWithOptions(io.NopCloser(strings.NewReader(
+						"data: "+strings.Repeat("A", 4090)+"event: forged"+strings.Repeat("B", 1000)+"\n"+
+							"data: real\n\n",
+					)), options)
+
+					_, err := readCloser.Next()
+					Ω(err).Should(Equal(EventTooLargeError{Limit: "MaxLineLength", Line: 1}))
+
+					_, err = readCloser.Next()
+					Ω(err).Should(Equal(EventTooLargeError{Limit: "MaxLineLength", Line: 1}))
+				})
+			})
+		})
+
+		Context("with a maximum event size", func() {
+			BeforeEach(func() {
+				options.MaxEventSize = 5
+			})
+
+			It("allows events up to the limit, including newlines", func() {
+				Ω(next("data: ab\ndata: cd\n\n")).Should(Equal(Event{Data: []byte("ab\ncd")}))
+			})
+
+			It("fails on larger events", func() {
+				_, err := next("data: abc\ndata: de\n\n")
+				Ω(err).Should(Equal(EventTooLargeError{Limit: "MaxEventSize", Line: 2}))
+			})
+		})
+
+		Context("with a maximum number of buffered bytes", func() {
+			BeforeEach(func() {
+				options.MaxBufferedBytes = 8
+			})
+
+			It("counts every field of the event", func() {
+				Ω(next("event: abcd\ndata: abcd\n\n")).Should(Equal(Event{Name: "abcd", Data: []byte("abcd")}))
+
+				_, err := next("id: 1\nevent: abcd\ndata: abcd\n\n")
+				Ω(err).Should(Equal(EventTooLargeError{Limit: "MaxBufferedBytes", Line: 3}))
+			})
+		})
+
+		Context("with a maximum number of fields", func() {
+			BeforeEach(func() {
+				options.MaxFields = 2
+			})
+
+			It("fails on events with more fields", func() {
+				Ω(next("data: a\n: comment\ndata: b\n\n")).Should(Equal(Event{Data: []byte("a\nb")}))
+
+				_, err := next("data: a\ndata: b\ndata: c\n\n")
+				Ω(err).Should(Equal(EventTooLargeError{Limit: "MaxFields", Line: 3}))
+			})
+		})
+
+		Context("when skipping oversized events", func() {
+			BeforeEach(func() {
+				options = ReaderOptions{
+					MaxLineLength:       20,
+					MaxEventSize:        10,
+					SkipOversizedEvents: true,
+				}
+			})
+
+			It("continues with the next event", func() {
+				readCloser := NewReadCloserWithOptions(io.NopCloser(strings.NewReader(
+					"id: 1\ndata: this line is much too long\ndata: a\n\n"+
+						"data: 12345\ndata: 67890\n\n"+
+						"data: ok\n\n",
+				)), options)
+
+				Ω(readCloser.Next()).Should(Equal(Event{ID: "1", Data: []byte("ok")}))
+
+				_, err := readCloser.Next()
+				Ω(err).Should(Equal(io.EOF))
+			})
+
+			It("keeps the retry delay of a skipped event", func() {
+				readCloser := NewReadCloserWithOptions(io.NopCloser(strings.NewReader(
+					"retry: 500\ndata: this line is much too long\n\n"+
+						"data: ok\n\n",
+				)), options)
+
+				Ω(readCloser.Next()).Should(Equal(Event{Retry: 500 * time.Millisecond, Data: []byte("ok")}))
+			})
+		})
+	})
+
+	Describe("surfacing comments, lines, and unknown fields", func() {
+		var (
+			stream   string
+			options  ReaderOptions
+			comments []string
+			lines    []string
+		)
+
+		BeforeEach(func() {
+			stream = ": heartbeat\n:no space\nid: 1\nvendor: extension\ncustom\ndata: hello\n\n"
+			comments = nil
+			lines = nil
+
+			options = ReaderOptions{
+				OnComment: func(comment string) {
+					comments = append(comments, comment)
+				},
+				OnLine: func(line []byte) {
+					lines = append(lines, string(line))
+				},
+				KeepUnknownFields: true,
+			}
+		})
+
+		JustBeforeEach(func() {
+			readCloser = NewReadCloserWithOptions(io.NopCloser(strings.NewReader(stream)), options)
+		})
+
+		It("reports them", func() {
+			Ω(readCloser.Next()).Should(Equal(Event{
+				ID:   "1",
+				Data: []byte("hello"),
+				Fields: []Field{
+					{Name: "vendor", Value: "extension"},
+					{Name: "custom", Value: ""},
+				},
+			}))
+
+			Ω(comments).Should(Equal([]string{"heartbeat", "no space"}))
+			Ω(lines).Should(Equal([]string{
+				": heartbeat",
+				":no space",
+				"id: 1",
+				"vendor: extension",
+				"custom",
+				"data: hello",
+				"",
+			}))
+		})
+
+		Context("when not enabled", func() {
+			BeforeEach(func() {
+				options = ReaderOptions{}
+			})
+
+			It("ignores them", func() {
+				Ω(readCloser.Next()).Should(Equal(Event{
+					ID:   "1",
+					Data: []byte("hello"),
+				}))
+			})
+		})
+
+		Context("when an event has unknown fields but no data", func() {
+			BeforeEach(func() {
+				stream = "vendor: discarded\n\ndata: hello\n\n"
+			})
+
+			It("does not carry them over to the next event", func() {
+				Ω(readCloser.Next()).Should(Equal(Event{
+					Data: []byte("hello"),
+				}))
+			})
+		})
+	})
+
+	Describe("reusing data", func() {
+		BeforeEach(func() {
+			readCloser = NewReadCloserWithOptions(io.NopCloser(strings.NewReader(
+				"data: first\n\ndata: second\n\n",
+			)), ReaderOptions{ReuseData: true})
+		})
+
+		It("overwrites the previous event's data", func() {
+			first, err := readCloser.Next()
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(first.Data).Should(Equal([]byte("first")))
+
+			second, err := readCloser.Next()
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(second.Data).Should(Equal([]byte("second")))
+			Ω(first.Data).Should(Equal([]byte("secon")))
+		})
+	})
+
+	Describe("without reusing data", func() {
+		BeforeEach(func() {
+			readCloser = NewReadCloser(io.NopCloser(strings.NewReader(
+				"data: first\n\ndata: second\n\n",
+			)))
+		})
+
+		It("returns data which is not overwritten", func() {
+			first, err := readCloser.Next()
+			Ω(err).ShouldNot(HaveOccurred())
+
+			_, err = readCloser.Next()
+			Ω(err).ShouldNot(HaveOccurred())
+
+			Ω(first.Data).Should(Equal([]byte("first")))
+		})
+	})
+
+	// examples from the event stream interpretation section of the spec
+	DescribeTable("conformance",
+		func(stream string, expected []Event) {
+			readCloser := NewReadCloser(io.NopCloser(strings.NewReader(stream)))
+
+			events := []Event{}
+			for event, err := range readCloser.All() {
+				Ω(err).ShouldNot(HaveOccurred())
+				events = append(events, event)
+			}
+
+			Ω(events).Should(Equal(expected))
+		},
+		Entry("multiple data lines",
+			"data: YHOO\ndata: +2\ndata: 10\n\n",
+			[]Event{
+				{Data: []byte("YHOO\n+2\n10")},
+			},
+		),
+		Entry("ids and resets",
+			": test stream\n\ndata: first event\nid: 1\n\ndata:second event\nid\n\ndata:  third event\n",
+			[]Event{
+				{ID: "1", Data: []byte("first event")},
+				{ID: "", ResetID: true, Data: []byte("second event")},
+			},
+		),
+		Entry("empty data lines",
+			"data\n\ndata\ndata\n\ndata:",
+			[]Event{
+				{Data: []byte{}},
+				{Data: []byte("\n")},
+			},
+		),
+		Entry("optional space after the colon",
+			"data:test\n\ndata: test\n\n",
+			[]Event{
+				{Data: []byte("test")},
+				{Data: []byte("test")},
+			},
+		),
+		Entry("event types",
+			"event: add\ndata: 73857293\n\nevent: remove\ndata: 2153\n\nevent: add\ndata: 113411\n\n",
+			[]Event{
+				{Name: "add", Data: []byte("73857293")},
+				{Name: "remove", Data: []byte("2153")},
+				{Name: "add", Data: []byte("113411")},
+			},
+		),
+		Entry("ids containing NULL",
+			"id: 1\ndata: a\n\nid: \x00\ndata: b\n\n",
+			[]Event{
+				{ID: "1", Data: []byte("a")},
+				{ID: "1", Data: []byte("b")},
+			},
+		),
+	)
+
+	Describe("All", func() {
+		BeforeEach(func() {
+			_, err := buffer.Write([]byte("id: 1\ndata: hello\n\nid: 2\ndata: hello again\n\n"))
+			Ω(err).ShouldNot(HaveOccurred())
+		})
+
+		It("iterates over events until EOF", func() {
+			var events []Event
+			for event, err := range readCloser.All() {
+				Ω(err).ShouldNot(HaveOccurred())
+				events = append(events, event)
+			}
+
+			Ω(events).Should(Equal([]Event{
+				{ID: "1", Data: []byte("hello")},
+				{ID: "2", Data: []byte("hello again")},
+			}))
+
+			Ω(readCloser.Close()).Should(HaveOccurred())
+		})
+
+		It("closes the stream when the loop breaks early", func() {
+			for event, err := range readCloser.All() {
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(event.ID).Should(Equal("1"))
+				break
+			}
+
+			Ω(readCloser.Close()).Should(HaveOccurred())
+		})
+
+		Context("when reading fails", func() {
+			var disaster = errors.New("oh no")
+
+			BeforeEach(func() {
+				readCloser = NewReadCloser(io.NopCloser(iotest.ErrReader(disaster)))
+			})
+
+			It("yields the error and stops", func() {
+				var errs []error
+				for _, err := range readCloser.All() {
+					errs = append(errs, err)
+				}
+
+				Ω(errs).Should(Equal([]error{disaster}))
+			})
+		})
+	})
+
 	Describe("Close", func() {
 		It("returns nil", func() {
 			err := readCloser.Close()
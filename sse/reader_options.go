@@ -0,0 +1,145 @@
+package sse
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// ReaderOptions configures how a ReadCloser parses a stream.
+type ReaderOptions struct {
+	// InvalidUTF8 determines how lines which are not valid UTF-8 are handled.
+	// Defaults to ReplaceInvalidUTF8, as required by the spec.
+	InvalidUTF8 InvalidUTF8Mode
+
+	// LastEventID is the ID assumed for events until the stream sets one, e.g.
+	// the last ID seen on a previous connection.
+	LastEventID string
+
+	// MaxLineLength is the maximum length of a line in bytes, excluding its
+	// line ending. Zero means no limit.
+	MaxLineLength int
+
+	// MaxEventSize is the maximum size of an event's data in bytes. Zero means
+	// no limit.
+	MaxEventSize int
+
+	// MaxBufferedBytes is the maximum total size in bytes of the field values
+	// buffered for a single event, e.g. its data, name, and id. Zero means no
+	// limit.
+	MaxBufferedBytes int
+
+	// MaxFields is the maximum number of fields in a single event. Zero means
+	// no limit.
+	MaxFields int
+
+	// SkipOversizedEvents discards an event which exceeds any of the limits
+	// and continues with the next one, rather than failing with an
+	// EventTooLargeError. The id and retry fields which preceded the
+	// offending line still take effect.
+	SkipOversizedEvents bool
+
+	// ReuseData makes the Data of each event returned by Next share a buffer
+	// which is overwritten by the following call, avoiding an allocation per
+	// event. Events must be copied if they are retained, so this should not
+	// be used with EventSource.Subscribe, which hands events to another
+	// goroutine.
+	ReuseData bool
+
+	// OnComment is called with the text of each comment line, i.e. those
+	// beginning with a colon, e.g. for servers which send comments as
+	// heartbeats. A single leading space is trimmed, as with field values.
+	OnComment func(comment string)
+
+	// OnLine is called with each line as it is read, before it is parsed,
+	// excluding its line ending. The line is only valid during the call.
+	OnLine func(line []byte)
+
+	// KeepUnknownFields collects fields other than id, event, data, and retry
+	// into the event's Fields, rather than ignoring them as the spec requires.
+	KeepUnknownFields bool
+}
+
+// InvalidUTF8Mode determines how a ReadCloser handles invalid UTF-8.
+type InvalidUTF8Mode int
+
+const (
+	// ReplaceInvalidUTF8 replaces each invalid byte with U+FFFD.
+	ReplaceInvalidUTF8 InvalidUTF8Mode = iota
+
+	// PassInvalidUTF8 leaves invalid bytes as they are, e.g. for streams
+	// carrying binary data.
+	PassInvalidUTF8
+
+	// RejectInvalidUTF8 fails with an InvalidUTF8Error.
+	RejectInvalidUTF8
+)
+
+// InvalidUTF8Error is returned by ReadCloser.Next when it encounters invalid
+// UTF-8 and is configured with RejectInvalidUTF8.
+type InvalidUTF8Error struct {
+	// Line is the line number of the invalid byte, starting from 1.
+	Line int
+
+	// Offset is the offset of the invalid byte from the start of the stream.
+	Offset int64
+}
+
+func (err InvalidUTF8Error) Error() string {
+	return fmt.Sprintf("invalid UTF-8 in event stream at line %d (offset %d)", err.Line, err.Offset)
+}
+
+// EventTooLargeError is returned by ReadCloser.Next when an event exceeds one
+// of the limits set by ReaderOptions. It matches ErrEventTooLarge.
+//
+// As the rest of the event is not read, the stream cannot be parsed any
+// further, so every later call to Next returns the same error.
+type EventTooLargeError struct {
+	// Limit is the name of the option which was exceeded, e.g. "MaxLineLength".
+	Limit string
+
+	// Line is the line number at which the limit was exceeded, starting from 1.
+	Line int
+}
+
+func (err EventTooLargeError) Error() string {
+	return fmt.Sprintf("%s: exceeded %s at line %d", ErrEventTooLarge, err.Limit, err.Line)
+}
+
+func (err EventTooLargeError) Is(target error) bool {
+	return target == ErrEventTooLarge
+}
+
+// invalidUTF8 returns the index of the first invalid byte in line, or -1.
+func invalidUTF8(line []byte) int {
+	if utf8.Valid(line) {
+		return -1
+	}
+
+	for i := 0; i < len(line); {
+		r, size := utf8.DecodeRune(line[i:])
+		if r == utf8.RuneError && size == 1 {
+			return i
+		}
+
+		i += size
+	}
+
+	return -1
+}
+
+// replaceInvalidUTF8 appends line to dst with each invalid byte replaced
+// with U+FFFD.
+func replaceInvalidUTF8(dst []byte, line []byte) []byte {
+	for i := 0; i < len(line); {
+		r, size := utf8.DecodeRune(line[i:])
+		if r == utf8.RuneError && size == 1 {
+			dst = utf8.AppendRune(dst, utf8.RuneError)
+		} else {
+			dst = append(dst, line[i:i+size]...)
+		}
+
+		i += size
+	}
+
+	return dst
+}
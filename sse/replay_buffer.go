@@ -0,0 +1,130 @@
+package sse
+
+import (
+	"sync"
+	"time"
+)
+
+// ReplayBuffer is an EventStore which retains recently published events in
+// memory.
+//
+// The buffer is bounded both by the number of events and, optionally, by
+// their age; whichever limit is hit first evicts the oldest events.
+type ReplayBuffer struct {
+	size   int
+	maxAge time.Duration
+
+	lock sync.Mutex
+
+	// ring of retained events, oldest first starting at start
+	entries []replayEntry
+	start   int
+	count   int
+}
+
+type replayEntry struct {
+	event Event
+	added time.Time
+}
+
+// NewReplayBuffer constructs a ReplayBuffer retaining at most size events. If
+// maxAge is non-zero, events older than maxAge are evicted as well.
+func NewReplayBuffer(size int, maxAge time.Duration) *ReplayBuffer {
+	if size < 1 {
+		size = 1
+	}
+
+	return &ReplayBuffer{
+		size:    size,
+		maxAge:  maxAge,
+		entries: make([]replayEntry, size),
+	}
+}
+
+// Append records the event, evicting the oldest event if the buffer is full.
+// It never fails.
+func (buffer *ReplayBuffer) Append(event Event) error {
+	buffer.lock.Lock()
+	defer buffer.lock.Unlock()
+
+	now := time.Now()
+
+	buffer.expire(now)
+
+	end := (buffer.start + buffer.count) % buffer.size
+	buffer.entries[end] = replayEntry{event: event, added: now}
+
+	if buffer.count == buffer.size {
+		buffer.start = (buffer.start + 1) % buffer.size
+	} else {
+		buffer.count++
+	}
+
+	return nil
+}
+
+// Since returns the retained events which were appended after the event with
+// the given ID, oldest first. If lastEventID is empty, every retained event is
+// returned.
+//
+// If no retained event has the given ID, either because it was never appended
+// or because it has since been evicted, ErrEventNotFound is returned.
+func (buffer *ReplayBuffer) Since(lastEventID string) ([]Event, error) {
+	buffer.lock.Lock()
+	defer buffer.lock.Unlock()
+
+	buffer.expire(time.Now())
+
+	from := 0
+	if lastEventID != "" {
+		found := false
+
+		// search from the newest event in case IDs were reused
+		for i := buffer.count - 1; i >= 0; i-- {
+			if buffer.at(i).event.ID == lastEventID {
+				from = i + 1
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return nil, ErrEventNotFound
+		}
+	}
+
+	events := make([]Event, 0, buffer.count-from)
+	for i := from; i < buffer.count; i++ {
+		events = append(events, buffer.at(i).event)
+	}
+
+	return events, nil
+}
+
+// Len returns the number of retained events.
+func (buffer *ReplayBuffer) Len() int {
+	buffer.lock.Lock()
+	defer buffer.lock.Unlock()
+
+	buffer.expire(time.Now())
+
+	return buffer.count
+}
+
+func (buffer *ReplayBuffer) at(i int) *replayEntry {
+	return &buffer.entries[(buffer.start+i)%buffer.size]
+}
+
+func (buffer *ReplayBuffer) expire(now time.Time) {
+	if buffer.maxAge == 0 {
+		return
+	}
+
+	for buffer.count > 0 && now.Sub(buffer.at(0).added) > buffer.maxAge {
+		// release the event's data
+		*buffer.at(0) = replayEntry{}
+
+		buffer.start = (buffer.start + 1) % buffer.size
+		buffer.count--
+	}
+}
@@ -0,0 +1,80 @@
+package sse_test
+
+import (
+	"fmt"
+	"time"
+
+	. "github.com/vito/go-sse/sse"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ReplayBuffer", func() {
+	var buffer *ReplayBuffer
+
+	event := func(id int) Event {
+		return Event{ID: fmt.Sprintf("%d", id), Data: []byte("data")}
+	}
+
+	BeforeEach(func() {
+		buffer = NewReplayBuffer(3, 0)
+	})
+
+	Describe("Since", func() {
+		BeforeEach(func() {
+			buffer.Append(event(1))
+			buffer.Append(event(2))
+			buffer.Append(event(3))
+		})
+
+		It("returns the events after the given ID", func() {
+			Ω(buffer.Since("1")).Should(Equal([]Event{event(2), event(3)}))
+		})
+
+		It("returns nothing after the latest event", func() {
+			Ω(buffer.Since("3")).Should(BeEmpty())
+		})
+
+		It("returns every event for an empty ID", func() {
+			Ω(buffer.Since("")).Should(Equal([]Event{event(1), event(2), event(3)}))
+		})
+
+		It("returns ErrEventNotFound for an unknown ID", func() {
+			_, err := buffer.Since("bogus")
+			Ω(err).Should(Equal(ErrEventNotFound))
+		})
+
+		Context("when the buffer overflows", func() {
+			BeforeEach(func() {
+				buffer.Append(event(4))
+			})
+
+			It("evicts the oldest event", func() {
+				Ω(buffer.Len()).Should(Equal(3))
+				Ω(buffer.Since("")).Should(Equal([]Event{event(2), event(3), event(4)}))
+
+				_, err := buffer.Since("1")
+				Ω(err).Should(Equal(ErrEventNotFound))
+			})
+		})
+	})
+
+	Context("with a maximum age", func() {
+		BeforeEach(func() {
+			buffer = NewReplayBuffer(10, 100*time.Millisecond)
+		})
+
+		It("evicts events older than the maximum age", func() {
+			buffer.Append(event(1))
+			time.Sleep(150 * time.Millisecond)
+			buffer.Append(event(2))
+
+			Ω(buffer.Len()).Should(Equal(1))
+			Ω(buffer.Since("")).Should(Equal([]Event{event(2)}))
+
+			_, err := buffer.Since("1")
+			Ω(err).Should(Equal(ErrEventNotFound))
+		})
+	})
+})
@@ -0,0 +1,169 @@
+package sse
+
+import (
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// RetryAttempt describes the circumstances of a reconnection, for a
+// RetryPolicy to decide how long to wait before it.
+type RetryAttempt struct {
+	// Attempt is the number of consecutive failed connection attempts. It is
+	// zero when reconnecting because an established stream was interrupted.
+	Attempt int
+
+	// Err is the error which ended the stream or failed the last attempt.
+	Err error
+
+	// StatusCode is the status of the last response if the server responded
+	// with a retryable status, and zero otherwise.
+	StatusCode int
+
+	// Previous is the delay which preceded the last attempt, or zero if the
+	// last attempt was the one which established the stream.
+	Previous time.Duration
+
+	// RetryAfter is the delay requested by the Retry-After header of the last
+	// response, if it was a 503 or 429 and the requested time has not already
+	// passed. When set, it is used in place of the policy's delay; the policy
+	// still decides whether to retry at all.
+	RetryAfter time.Duration
+
+	// the number of consecutive attempts which failed without a response,
+	// which is all that RetryParams.MaxRetries limits
+	failedRequests int
+}
+
+// RetryPolicy decides how long an EventSource waits before reconnecting.
+type RetryPolicy interface {
+	// NextDelay returns the delay before the next attempt, or false to give up
+	// and return the attempt's error.
+	NextDelay(RetryAttempt) (time.Duration, bool)
+}
+
+// ServerRetryMode determines how the retry interval sent by the server in an
+// event's retry field interacts with the RetryPolicy.
+type ServerRetryMode int
+
+const (
+	// ServerRetryOverride uses the server's retry interval in place of the
+	// policy's delay. The policy still decides when to give up.
+	ServerRetryOverride ServerRetryMode = iota
+
+	// ServerRetryFloor uses the server's retry interval as the minimum delay.
+	ServerRetryFloor
+
+	// ServerRetryIgnore always uses the policy's delay.
+	ServerRetryIgnore
+)
+
+// ConstantBackoff waits the same interval before every attempt.
+type ConstantBackoff struct {
+	Interval time.Duration
+
+	// MaxRetries is the number of consecutive failed attempts after which to
+	// give up. Zero means retry forever.
+	MaxRetries int
+}
+
+func (policy ConstantBackoff) NextDelay(attempt RetryAttempt) (time.Duration, bool) {
+	if exhausted(policy.MaxRetries, attempt) {
+		return 0, false
+	}
+
+	return policy.Interval, true
+}
+
+// ExponentialBackoff multiplies the delay after each consecutive failed
+// attempt, up to a maximum, optionally randomizing it so that many clients
+// do not reconnect in lockstep.
+type ExponentialBackoff struct {
+	// Initial is the delay before reconnecting after the stream is
+	// interrupted.
+	Initial time.Duration
+
+	// Max caps the delay. Zero means no cap.
+	Max time.Duration
+
+	// Multiplier is the factor by which the delay grows with each failed
+	// attempt. Defaults to 2.
+	Multiplier float64
+
+	// Jitter is the fraction of each delay, from 0 to 1, which is randomized.
+	// With a Jitter of 0.5, a delay of 10s becomes anything from 5s to 10s;
+	// with a Jitter of 1 ("full jitter"), anything from 0s to 10s.
+	Jitter float64
+
+	// MaxRetries is the number of consecutive failed attempts after which to
+	// give up. Zero means retry forever.
+	MaxRetries int
+}
+
+func (policy ExponentialBackoff) NextDelay(attempt RetryAttempt) (time.Duration, bool) {
+	if exhausted(policy.MaxRetries, attempt) {
+		return 0, false
+	}
+
+	multiplier := policy.Multiplier
+	if multiplier == 0 {
+		multiplier = 2
+	}
+
+	delay := float64(policy.Initial) * math.Pow(multiplier, float64(attempt.Attempt))
+	if policy.Max != 0 && delay > float64(policy.Max) {
+		delay = float64(policy.Max)
+	}
+
+	if policy.Jitter > 0 {
+		delay -= delay * math.Min(policy.Jitter, 1) * rand.Float64()
+	}
+
+	return time.Duration(delay), true
+}
+
+// DecorrelatedJitter picks each delay at random between Base and three times
+// the previous delay, capped at Cap. This spreads out reconnecting clients
+// more evenly than ExponentialBackoff while still backing off.
+type DecorrelatedJitter struct {
+	Base time.Duration
+	Cap  time.Duration
+
+	// MaxRetries is the number of consecutive failed attempts after which to
+	// give up. Zero means retry forever.
+	MaxRetries int
+}
+
+func (policy DecorrelatedJitter) NextDelay(attempt RetryAttempt) (time.Duration, bool) {
+	if exhausted(policy.MaxRetries, attempt) {
+		return 0, false
+	}
+
+	previous := max(attempt.Previous, policy.Base)
+
+	delay := policy.Base
+	if spread := 3*previous - policy.Base; spread > 0 {
+		delay += rand.N(spread)
+	}
+
+	if policy.Cap != 0 && delay > policy.Cap {
+		delay = policy.Cap
+	}
+
+	return delay, true
+}
+
+// retryParamsBackoff is the ConstantBackoff configured by RetryParams, which
+// retries error responses indefinitely.
+type retryParamsBackoff struct {
+	ConstantBackoff
+}
+
+func (policy retryParamsBackoff) NextDelay(attempt RetryAttempt) (time.Duration, bool) {
+	attempt.Attempt = attempt.failedRequests
+	return policy.ConstantBackoff.NextDelay(attempt)
+}
+
+func exhausted(maxRetries int, attempt RetryAttempt) bool {
+	return maxRetries > 0 && attempt.Attempt > maxRetries
+}
@@ -0,0 +1,128 @@
+package sse_test
+
+import (
+	"errors"
+	"time"
+
+	. "github.com/vito/go-sse/sse"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RetryPolicy", func() {
+	attempt := func(n int) RetryAttempt {
+		return RetryAttempt{Attempt: n, Err: errors.New("oh no")}
+	}
+
+	delayOf := func(policy RetryPolicy, attempt RetryAttempt) time.Duration {
+		delay, retry := policy.NextDelay(attempt)
+		Ω(retry).Should(BeTrue())
+		return delay
+	}
+
+	Describe("ConstantBackoff", func() {
+		var policy ConstantBackoff
+
+		BeforeEach(func() {
+			policy = ConstantBackoff{Interval: time.Second, MaxRetries: 2}
+		})
+
+		It("always waits the interval", func() {
+			Ω(delayOf(policy, attempt(0))).Should(Equal(time.Second))
+			Ω(delayOf(policy, attempt(2))).Should(Equal(time.Second))
+		})
+
+		It("gives up after the max retries", func() {
+			_, retry := policy.NextDelay(attempt(3))
+			Ω(retry).Should(BeFalse())
+		})
+
+		It("retries forever without max retries", func() {
+			policy.MaxRetries = 0
+			Ω(delayOf(policy, attempt(1000))).Should(Equal(time.Second))
+		})
+	})
+
+	Describe("ExponentialBackoff", func() {
+		var policy ExponentialBackoff
+
+		BeforeEach(func() {
+			policy = ExponentialBackoff{
+				Initial: 100 * time.Millisecond,
+				Max:     time.Second,
+			}
+		})
+
+		It("doubles the delay with each failed attempt up to the max", func() {
+			Ω(delayOf(policy, attempt(0))).Should(Equal(100 * time.Millisecond))
+			Ω(delayOf(policy, attempt(1))).Should(Equal(200 * time.Millisecond))
+			Ω(delayOf(policy, attempt(3))).Should(Equal(800 * time.Millisecond))
+			Ω(delayOf(policy, attempt(4))).Should(Equal(time.Second))
+			Ω(delayOf(policy, attempt(100))).Should(Equal(time.Second))
+		})
+
+		It("uses the given multiplier", func() {
+			policy.Multiplier = 3
+			Ω(delayOf(policy, attempt(2))).Should(Equal(900 * time.Millisecond))
+		})
+
+		It("randomizes the jittered fraction of the delay", func() {
+			policy.Jitter = 0.5
+
+			for range 100 {
+				delay, retry := policy.NextDelay(attempt(3))
+				Ω(retry).Should(BeTrue())
+				Ω(delay).Should(BeNumerically(">=", 400*time.Millisecond))
+				Ω(delay).Should(BeNumerically("<=", 800*time.Millisecond))
+			}
+		})
+
+		It("gives up after the max retries", func() {
+			policy.MaxRetries = 3
+
+			_, retry := policy.NextDelay(attempt(4))
+			Ω(retry).Should(BeFalse())
+		})
+	})
+
+	Describe("DecorrelatedJitter", func() {
+		var policy DecorrelatedJitter
+
+		BeforeEach(func() {
+			policy = DecorrelatedJitter{
+				Base: 100 * time.Millisecond,
+				Cap:  time.Second,
+			}
+		})
+
+		It("picks a delay between the base and three times the previous delay", func() {
+			for range 100 {
+				delay, retry := policy.NextDelay(RetryAttempt{
+					Attempt:  2,
+					Previous: 200 * time.Millisecond,
+				})
+				Ω(retry).Should(BeTrue())
+				Ω(delay).Should(BeNumerically(">=", 100*time.Millisecond))
+				Ω(delay).Should(BeNumerically("<", 600*time.Millisecond))
+			}
+		})
+
+		It("never exceeds the cap", func() {
+			for range 100 {
+				delay, _ := policy.NextDelay(RetryAttempt{
+					Attempt:  10,
+					Previous: time.Second,
+				})
+				Ω(delay).Should(BeNumerically("<=", time.Second))
+			}
+		})
+
+		It("gives up after the max retries", func() {
+			policy.MaxRetries = 1
+
+			_, retry := policy.NextDelay(attempt(2))
+			Ω(retry).Should(BeFalse())
+		})
+	})
+})
@@ -0,0 +1,141 @@
+package sse
+
+import (
+	"context"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+// OverflowPolicy determines what a Subscription does with new events when its
+// buffer is full because the consumer has fallen behind.
+type OverflowPolicy int
+
+const (
+	// Block stops reading from the stream until the consumer catches up, so
+	// that no events are lost.
+	Block OverflowPolicy = iota
+
+	// DropOldest discards the oldest buffered event to make room.
+	DropOldest
+
+	// DropNewest discards the new event.
+	DropNewest
+)
+
+// SubscribeOptions configures a Subscription.
+type SubscribeOptions struct {
+	// BufferSize is the number of events buffered for the consumer. Zero means
+	// unbuffered with Block, so each event is handed to the consumer directly.
+	// DropOldest and DropNewest always buffer at least one event, as there
+	// would otherwise be nowhere to keep an event until the consumer is ready.
+	BufferSize int
+
+	// Overflow determines what happens when the buffer is full. Defaults to
+	// Block.
+	Overflow OverflowPolicy
+}
+
+// Subscription delivers the events read from an EventSource on a channel.
+type Subscription struct {
+	events  chan Event
+	dropped atomic.Uint64
+
+	lock sync.Mutex
+	err  error
+}
+
+// Subscribe starts reading events from the source in the background and
+// delivering them to the returned Subscription, reconnecting as usual.
+//
+// The subscription ends when the context is cancelled, the stream ends, or the
+// source fails or is closed, at which point its Events channel is closed and
+// Err reports why. Cancelling the context does not close the source.
+func (source *EventSource) Subscribe(ctx context.Context, options SubscribeOptions) *Subscription {
+	bufferSize := options.BufferSize
+	if options.Overflow != Block {
+		bufferSize = max(bufferSize, 1)
+	}
+
+	sub := &Subscription{
+		events: make(chan Event, bufferSize),
+	}
+
+	go sub.run(ctx, source, options.Overflow)
+
+	return sub
+}
+
+// Events returns the channel on which events are delivered. It is closed when
+// the subscription ends.
+func (sub *Subscription) Events() <-chan Event {
+	return sub.events
+}
+
+// Err returns the error which ended the subscription, or nil if it has not
+// ended or the stream ended normally.
+func (sub *Subscription) Err() error {
+	sub.lock.Lock()
+	defer sub.lock.Unlock()
+
+	return sub.err
+}
+
+// Dropped returns the number of events discarded because the buffer was full.
+func (sub *Subscription) Dropped() uint64 {
+	return sub.dropped.Load()
+}
+
+func (sub *Subscription) run(ctx context.Context, source *EventSource, overflow OverflowPolicy) {
+	defer close(sub.events)
+
+	for {
+		event, err := source.NextContext(ctx)
+		if err != nil {
+			sub.end(err)
+			return
+		}
+
+		switch overflow {
+		case DropNewest:
+			select {
+			case sub.events <- event:
+			default:
+				sub.dropped.Add(1)
+			}
+
+		case DropOldest:
+			for delivered := false; !delivered; {
+				select {
+				case sub.events <- event:
+					delivered = true
+				default:
+					select {
+					case <-sub.events:
+						sub.dropped.Add(1)
+					default:
+						// the consumer made room in the meantime
+					}
+				}
+			}
+
+		default:
+			select {
+			case sub.events <- event:
+			case <-ctx.Done():
+				sub.end(ctx.Err())
+				return
+			}
+		}
+	}
+}
+
+func (sub *Subscription) end(err error) {
+	if err == io.EOF {
+		err = nil
+	}
+
+	sub.lock.Lock()
+	sub.err = err
+	sub.lock.Unlock()
+}
@@ -0,0 +1,196 @@
+package sse_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	. "github.com/vito/go-sse/sse"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+)
+
+var _ = Describe("Subscription", func() {
+	var (
+		server *ghttp.Server
+		source *EventSource
+
+		ctx    context.Context
+		cancel context.CancelFunc
+
+		options SubscribeOptions
+		sub     *Subscription
+
+		eventCount int
+		hang       chan struct{}
+	)
+
+	event := func(id int) Event {
+		return Event{ID: fmt.Sprintf("%d", id), Data: []byte("hello")}
+	}
+
+	BeforeEach(func() {
+		server = ghttp.NewServer()
+
+		source = NewEventSource(http.DefaultClient, 100*time.Millisecond, func() *http.Request {
+			request, err := http.NewRequest("GET", server.URL(), nil)
+			Ω(err).ShouldNot(HaveOccurred())
+			return request
+		})
+
+		ctx, cancel = context.WithCancel(context.Background())
+
+		options = SubscribeOptions{}
+		eventCount = 3
+		hang = nil
+	})
+
+	JustBeforeEach(func() {
+		count := eventCount
+		block := hang
+
+		server.AppendHandlers(
+			func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Add("Content-Type", "text/event-stream; charset=utf-8")
+				w.WriteHeader(http.StatusOK)
+
+				for i := 1; i <= count; i++ {
+					event(i).Write(w)
+				}
+
+				w.(http.Flusher).Flush()
+
+				if block != nil {
+					select {
+					case <-block:
+					case <-r.Context().Done():
+					}
+				}
+			},
+		)
+
+		sub = source.Subscribe(ctx, options)
+	})
+
+	AfterEach(func() {
+		cancel()
+		source.Close()
+		server.Close()
+	})
+
+	It("delivers events until the stream ends", func() {
+		Eventually(sub.Events()).Should(Receive(Equal(event(1))))
+		Eventually(sub.Events()).Should(Receive(Equal(event(2))))
+		Eventually(sub.Events()).Should(Receive(Equal(event(3))))
+		Eventually(sub.Events()).Should(BeClosed())
+
+		Ω(sub.Err()).ShouldNot(HaveOccurred())
+	})
+
+	Context("when the stream stays open", func() {
+		BeforeEach(func() {
+			hang = make(chan struct{})
+		})
+
+		It("ends with the context's error when it is cancelled", func() {
+			Eventually(sub.Events()).Should(Receive(Equal(event(1))))
+
+			cancel()
+
+			Eventually(sub.Events()).Should(BeClosed())
+			Ω(sub.Err()).Should(Equal(context.Canceled))
+
+			Ω(source.ReadyState()).ShouldNot(Equal(Closed))
+		})
+
+		It("ends with ErrSourceClosed when the source is closed", func() {
+			Eventually(sub.Events()).Should(Receive(Equal(event(1))))
+			Eventually(sub.Events()).Should(Receive(Equal(event(2))))
+			Eventually(sub.Events()).Should(Receive(Equal(event(3))))
+
+			Ω(source.Close()).Should(Succeed())
+
+			Eventually(sub.Events()).Should(BeClosed())
+			Ω(sub.Err()).Should(Equal(ErrSourceClosed))
+		})
+
+		Context("when the consumer falls behind", func() {
+			BeforeEach(func() {
+				options.BufferSize = 1
+			})
+
+			Context("and newest events are dropped", func() {
+				BeforeEach(func() {
+					options.Overflow = DropNewest
+				})
+
+				It("keeps the buffered event", func() {
+					Eventually(sub.Dropped).Should(BeEquivalentTo(2))
+					Ω(sub.Events()).Should(Receive(Equal(event(1))))
+				})
+			})
+
+			Context("and oldest events are dropped", func() {
+				BeforeEach(func() {
+					options.Overflow = DropOldest
+				})
+
+				It("keeps the latest event", func() {
+					Eventually(sub.Dropped).Should(BeEquivalentTo(2))
+					Ω(sub.Events()).Should(Receive(Equal(event(3))))
+				})
+			})
+
+			Context("and the subscription blocks", func() {
+				It("delivers every event", func() {
+					Consistently(sub.Dropped).Should(BeZero())
+
+					for i := 1; i <= 3; i++ {
+						Eventually(sub.Events()).Should(Receive(Equal(event(i))))
+					}
+				})
+			})
+		})
+
+		Context("when the consumer falls behind with no buffer", func() {
+			BeforeEach(func() {
+				options.BufferSize = 0
+			})
+
+			Context("and newest events are dropped", func() {
+				BeforeEach(func() {
+					options.Overflow = DropNewest
+				})
+
+				It("buffers one event anyway", func() {
+					Eventually(sub.Dropped).Should(BeEquivalentTo(2))
+					Ω(sub.Events()).Should(Receive(Equal(event(1))))
+				})
+			})
+
+			Context("and oldest events are dropped", func() {
+				BeforeEach(func() {
+					options.Overflow = DropOldest
+				})
+
+				It("buffers one event anyway", func() {
+					Eventually(sub.Dropped).Should(BeEquivalentTo(2))
+					Ω(sub.Events()).Should(Receive(Equal(event(3))))
+				})
+			})
+
+			Context("and the subscription blocks", func() {
+				It("hands each event to the consumer directly", func() {
+					Consistently(sub.Dropped).Should(BeZero())
+
+					for i := 1; i <= 3; i++ {
+						Eventually(sub.Events()).Should(Receive(Equal(event(i))))
+					}
+				})
+			})
+		})
+	})
+})